@@ -2,16 +2,21 @@ package arraylog
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/xuri/excelize/v2"
 )
 
-// Sample represents a row from the "IFM Queue" sheet in the array log. There is
-// additional clinical information in the "Sample Log" sheet; however, it is
-// mostly unpopulated as we are not processing clinical samples at the moment.
-// The "Sample Log" sheet is currently not parsed. These clinical columns also
+const (
+	ifmQueueSheet  = "IFM Queue"
+	sampleLogSheet = "Sample Log"
+)
+
+// Sample represents a row from the "IFM Queue" or "Sample Log" sheet in the
+// array log. The "IFM Queue" sheet holds the genotyping information while the
+// "Sample Log" sheet holds the clinical information. The clinical columns also
 // appear in the "IFM Queue" sheet, but it is not clear if they are guarenteed
-// to be populated.
+// to be populated, so Merge prefers the values from the "Sample Log" sheet.
 type Sample struct {
 	UIN                string
 	SubjectID          string
@@ -26,95 +31,183 @@ type Sample struct {
 	GenotypingCallRate string
 	Exclude            string
 	ExcludeReason      string
+
+	// Clinical information.
+	URN                 string
+	PatientName         string
+	DOB                 string
+	Gender              string
+	SampleType          string
+	Disease             string
+	RequestingClinician string
+	ReceiptDate         string
 }
 
+// Scanner reads samples from a sheet of the array log one row at a time.
 type Scanner struct {
 	err        error
 	f          *excelize.File
+	rows       *excelize.Rows
+	row        []string
 	headerMap  map[string]int
 	sheetName  string
-	curRow     int
 	nextSample Sample
 }
 
-func NewScanner(fn string) (*Scanner, error) {
-	f, err := excelize.OpenFile(fn)
+// Default columns used to find the header row of each sheet.
+var (
+	DefaultIFMQueueColumns  = []string{"UIN", "Infinium ID"}
+	DefaultSampleLogColumns = []string{"UIN"}
+)
+
+// defaultMaxHeaderRow is how far down a sheet the header row is searched for.
+const defaultMaxHeaderRow = 10
+
+// Options controls how the array log is opened.
+type Options struct {
+	// Password is used to open an encrypted workbook.
+	Password string
+	// IFMQueueColumns and SampleLogColumns are the column names that
+	// identify the header row of each sheet. The first row containing all of
+	// them is the header and samples start on the following row. When nil,
+	// DefaultIFMQueueColumns and DefaultSampleLogColumns are used.
+	IFMQueueColumns  []string
+	SampleLogColumns []string
+	// MaxHeaderRow is the last row searched for the header. Zero searches
+	// the first 10 rows.
+	MaxHeaderRow int
+}
+
+// NewScanner returns a Scanner for the "IFM Queue" sheet of the array log. Pass
+// Options{} for an unencrypted workbook with the default columns.
+func NewScanner(fn string, opts Options) (*Scanner, error) {
+	return newScanner(fn, ifmQueueSheet, opts)
+}
+
+// NewSampleLogScanner returns a Scanner for the "Sample Log" sheet of the array
+// log. Only the UIN, SubjectID, ProjectID and clinical fields are populated.
+func NewSampleLogScanner(fn string, opts Options) (*Scanner, error) {
+	return newScanner(fn, sampleLogSheet, opts)
+}
+
+func newScanner(fn, sheet string, o Options) (*Scanner, error) {
+	f, err := excelize.OpenFile(fn, excelize.Options{Password: o.Password})
 	if err != nil {
 		return &Scanner{}, err
 	}
-	sheet := "IFM Queue"
-	headerMap, err := getHeaderMap(f, sheet)
+	rows, err := f.Rows(sheet)
 	if err != nil {
 		return &Scanner{}, err
 	}
+	required := o.IFMQueueColumns
+	if required == nil {
+		required = DefaultIFMQueueColumns
+	}
+	if sheet == sampleLogSheet {
+		required = o.SampleLogColumns
+		if required == nil {
+			required = DefaultSampleLogColumns
+		}
+	}
+	maxRow := o.MaxHeaderRow
+	if maxRow == 0 {
+		maxRow = defaultMaxHeaderRow
+	}
+	headerMap, err := findHeader(rows, required, maxRow)
+	if err != nil {
+		return &Scanner{}, fmt.Errorf("sheet '%s': %w", sheet, err)
+	}
 	return &Scanner{
 		err:       nil,
 		f:         f,
-		curRow:    2,
+		rows:      rows,
 		headerMap: headerMap,
 		sheetName: sheet,
 	}, nil
 }
 
 func (s *Scanner) Scan() bool {
-	uin, err := s.getFormattedString("UIN", s.curRow)
+	if !s.rows.Next() {
+		s.err = s.rows.Error()
+		return false
+	}
+	row, err := s.rows.Columns()
 	if err != nil {
 		s.err = err
 		return false
 	}
-	if uin == "" {
-		s.err = nil
-		return false
+	s.row = row
+	var sample Sample
+	if s.sheetName == sampleLogSheet {
+		sample, err = s.readSampleLogSample()
+	} else {
+		sample, err = s.readIFMQueueSample()
 	}
-	subjectID, err := s.getFormattedString("SubjectID", s.curRow)
 	if err != nil {
 		s.err = err
 		return false
 	}
-	projectID, err := s.getFormattedString("ProjectID", s.curRow)
-	if err != nil {
-		s.err = err
+	if sample.UIN == "" {
+		s.err = nil
 		return false
 	}
-	beadChipVersion, err := s.getFormattedString("Beadchip version", s.curRow)
+	s.nextSample = sample
+	s.err = nil
+	return true
+}
+
+func (s *Scanner) readIFMQueueSample() (Sample, error) {
+	uin, err := s.getFormattedString("UIN")
 	if err != nil {
-		s.err = err
-		return false
+		return Sample{}, err
 	}
-	sentrixID, err := s.getFormattedString("BeadChip Sentrix ID", s.curRow)
+	if uin == "" {
+		return Sample{}, nil
+	}
+	subjectID, err := s.getFormattedString("SubjectID")
 	if err != nil {
-		s.err = err
-		return false
+		return Sample{}, err
 	}
-	sentrixPosition, err := s.getFormattedString("Beadchip Sentrix Position", s.curRow)
+	projectID, err := s.getFormattedString("ProjectID")
 	if err != nil {
-		s.err = err
-		return false
+		return Sample{}, err
 	}
-	exclude, err := s.getFormattedString("Exclude", s.curRow)
+	beadChipVersion, err := s.getFormattedString("Beadchip version")
 	if err != nil {
-		s.err = err
-		return false
+		return Sample{}, err
 	}
-	numInAssay, err := s.getFormattedString("# in assay", s.curRow)
+	sentrixID, err := s.getFormattedString("BeadChip Sentrix ID")
 	if err != nil {
-		s.err = err
-		return false
+		return Sample{}, err
 	}
-	beadChipBatch, err := s.getFormattedString("Beadchip batch", s.curRow)
+	sentrixPosition, err := s.getFormattedString("Beadchip Sentrix Position")
 	if err != nil {
-		s.err = err
-		return false
+		return Sample{}, err
 	}
-	genotypingCallRate, err := s.getFormattedString("Genotyping call rate ", s.curRow)
+	exclude, err := s.getFormattedString("Exclude")
 	if err != nil {
-		s.err = err
-		return false
+		return Sample{}, err
 	}
-	infinumuID, err := s.getFormattedString("Infinium ID", s.curRow)
+	// Older logs do not have an "Exclude reason" column.
+	excludeReason, err := s.getOptionalString("Exclude reason")
 	if err != nil {
-		s.err = err
-		return false
+		return Sample{}, err
+	}
+	numInAssay, err := s.getFormattedString("# in assay")
+	if err != nil {
+		return Sample{}, err
+	}
+	beadChipBatch, err := s.getFormattedString("Beadchip batch")
+	if err != nil {
+		return Sample{}, err
+	}
+	genotypingCallRate, err := s.getFormattedString("Genotyping call rate ")
+	if err != nil {
+		return Sample{}, err
+	}
+	infinumuID, err := s.getFormattedString("Infinium ID")
+	if err != nil {
+		return Sample{}, err
 	}
 	sample := Sample{
 		UIN:                uin,
@@ -126,14 +219,69 @@ func (s *Scanner) Scan() bool {
 		SentrixPosition:    sentrixPosition,
 		IlluminaID:         sentrixID + "_" + sentrixPosition,
 		Exclude:            exclude,
+		ExcludeReason:      excludeReason,
 		NumInAssay:         numInAssay,
 		BeadChipBatch:      beadChipBatch,
 		GenotypingCallRate: genotypingCallRate,
 	}
-	s.nextSample = sample
-	s.err = nil
-	s.curRow++
-	return true
+	// The clinical columns are not guarenteed to be present in the "IFM
+	// Queue" sheet.
+	if err := s.readClinical(&sample); err != nil {
+		return Sample{}, err
+	}
+	return sample, nil
+}
+
+func (s *Scanner) readSampleLogSample() (Sample, error) {
+	uin, err := s.getFormattedString("UIN")
+	if err != nil {
+		return Sample{}, err
+	}
+	if uin == "" {
+		return Sample{}, nil
+	}
+	subjectID, err := s.getOptionalString("SubjectID")
+	if err != nil {
+		return Sample{}, err
+	}
+	projectID, err := s.getOptionalString("ProjectID")
+	if err != nil {
+		return Sample{}, err
+	}
+	sample := Sample{
+		UIN:       uin,
+		SubjectID: subjectID,
+		ProjectID: projectID,
+	}
+	if err := s.readClinical(&sample); err != nil {
+		return Sample{}, err
+	}
+	return sample, nil
+}
+
+func (s *Scanner) readClinical(sample *Sample) error {
+	fields := []struct {
+		column string
+		value  *string
+	}{
+		{"UR", &sample.URN},
+		{"PatientName", &sample.PatientName},
+		{"DOB", &sample.DOB},
+		{"Gender", &sample.Gender},
+		{"SampleType", &sample.SampleType},
+		{"Disease", &sample.Disease},
+		{"Requesting Clinician", &sample.RequestingClinician},
+		{"Receipt date", &sample.ReceiptDate},
+	}
+	for _, field := range fields {
+		v, err := s.getOptionalString(field.column)
+		if err != nil {
+			return err
+		}
+		*field.value = v
+	}
+	sample.Gender = strings.ToUpper(sample.Gender)
+	return nil
 }
 
 func (s *Scanner) Error() error {
@@ -144,41 +292,68 @@ func (s *Scanner) Sample() Sample {
 	return s.nextSample
 }
 
-func (s *Scanner) getFormattedString(column string, rowIdx int) (string, error) {
+// getFormattedString returns the value of column in the current row.
+func (s *Scanner) getFormattedString(column string) (string, error) {
 	idx, ok := s.headerMap[column]
 	if !ok {
 		return "", fmt.Errorf("unable to find index for '%s' column", column)
 	}
-	cellName, err := excelize.CoordinatesToCellName(idx+1, rowIdx+1)
-	if err != nil {
-		return "", fmt.Errorf("failed to get coordinate: %w", err)
+	// Trailing empty cells are not included in the row.
+	if idx >= len(s.row) {
+		return "", nil
 	}
-	c, err := s.f.GetCellValue(s.sheetName, cellName)
+	c := s.row[idx]
 	if c == "NA" {
 		c = ""
 	}
-	return c, err
+	return c, nil
 }
 
-func getHeaderMap(f *excelize.File, sheet string) (map[string]int, error) {
-	m := make(map[string]int)
-	i := 0
-	rows, err := f.Rows(sheet)
-	if err != nil {
-		return m, err
+// getOptionalString is like getFormattedString but returns an empty string
+// when the column is not present in the sheet.
+func (s *Scanner) getOptionalString(column string) (string, error) {
+	if _, ok := s.headerMap[column]; !ok {
+		return "", nil
 	}
-	for rows.Next() {
-		i++
+	return s.getFormattedString(column)
+}
+
+// findHeader searches the first maxRow rows for one that contains all the
+// required columns and returns its column indices. rows is left positioned at
+// the header so the next row is the first sample. If no row matches, the
+// error lists the missing columns for every non-empty row searched.
+func findHeader(rows *excelize.Rows, required []string, maxRow int) (map[string]int, error) {
+	candidates := []string{}
+	for i := 1; i <= maxRow && rows.Next(); i++ {
 		row, err := rows.Columns()
 		if err != nil {
-			return m, err
+			return nil, err
 		}
-		if i == 2 {
-			for ci, col := range row {
+		m := make(map[string]int)
+		for ci, col := range row {
+			if col != "" {
 				m[col] = ci
 			}
-			break
 		}
+		if len(m) == 0 {
+			continue
+		}
+		missing := []string{}
+		for _, col := range required {
+			if _, ok := m[col]; !ok {
+				missing = append(missing, col)
+			}
+		}
+		if len(missing) == 0 {
+			return m, nil
+		}
+		candidates = append(candidates, fmt.Sprintf("row %d missing %s", i, strings.Join(missing, ", ")))
+	}
+	if err := rows.Error(); err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no header row found in the first %d rows: all rows are empty", maxRow)
 	}
-	return m, nil
+	return nil, fmt.Errorf("no header row found in the first %d rows: %s", maxRow, strings.Join(candidates, "; "))
 }
@@ -0,0 +1,94 @@
+package arraylog
+
+import "strings"
+
+// ExclusionReason categorises why a sample was excluded.
+type ExclusionReason int
+
+const (
+	ReasonNone ExclusionReason = iota
+	ReasonLowCallRate
+	ReasonSexMismatch
+	ReasonDuplicate
+	ReasonWithdrawn
+	ReasonOther
+)
+
+func (r ExclusionReason) String() string {
+	switch r {
+	case ReasonNone:
+		return "none"
+	case ReasonLowCallRate:
+		return "low call rate"
+	case ReasonSexMismatch:
+		return "sex mismatch"
+	case ReasonDuplicate:
+		return "duplicate"
+	case ReasonWithdrawn:
+		return "withdrawn"
+	default:
+		return "other"
+	}
+}
+
+// Exclusion is the structured form of the Exclude and ExcludeReason columns.
+type Exclusion struct {
+	Excluded bool
+	Reason   ExclusionReason
+	// Note is the free text the lab entered, if any.
+	Note string
+}
+
+// Exclusion interprets the Exclude and ExcludeReason fields of the sample. The
+// Exclude column is usually "Yes" or blank, but sometimes the reason has been
+// typed straight into it; anything other than a recognised "no" value is
+// treated as an exclusion.
+func (s Sample) Exclusion() Exclusion {
+	flag := strings.TrimSpace(s.Exclude)
+	note := strings.TrimSpace(s.ExcludeReason)
+	switch strings.ToUpper(flag) {
+	case "", "N", "NO", "FALSE", "0":
+		return Exclusion{}
+	case "Y", "YES", "TRUE", "1", "X":
+	default:
+		if note == "" {
+			note = flag
+		}
+	}
+	return Exclusion{
+		Excluded: true,
+		Reason:   ParseExclusionReason(note),
+		Note:     note,
+	}
+}
+
+// ParseExclusionReason maps the free text of an exclude reason onto a reason
+// category. Text that does not match a known category is ReasonOther.
+func ParseExclusionReason(text string) ExclusionReason {
+	t := strings.ToLower(text)
+	switch {
+	case strings.Contains(t, "call rate") || strings.Contains(t, "callrate") || strings.Contains(t, "low cr"):
+		return ReasonLowCallRate
+	case strings.Contains(t, "sex") || strings.Contains(t, "gender"):
+		return ReasonSexMismatch
+	case strings.Contains(t, "dup"):
+		return ReasonDuplicate
+	case strings.Contains(t, "withdr") || strings.Contains(t, "consent"):
+		return ReasonWithdrawn
+	default:
+		return ReasonOther
+	}
+}
+
+// Partition splits samples into those the lab meant to keep and those marked
+// as excluded. The order of samples is preserved.
+func Partition(samples []Sample) (included, excluded []Sample) {
+	for _, sample := range samples {
+		if sample.Exclusion().Excluded {
+			excluded = append(excluded, sample)
+		} else {
+			included = append(included, sample)
+		}
+	}
+	return included, excluded
+}
@@ -0,0 +1,162 @@
+package arraylog
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ChipGrid lays out the samples on a single BeadChip as they are physically
+// loaded. Cells[r][c] holds the sample at position R<r+1>C<c+1>, or nil if the
+// position is empty.
+type ChipGrid struct {
+	SentrixID       string
+	BeadChipVersion string
+	Layout          ChipLayout
+	Cells           [][]*Sample
+}
+
+// Grids groups samples by SentrixID and lays each chip out according to its
+// BeadChipVersion. Samples without a Sentrix ID are ignored. Grids are sorted
+// by SentrixID.
+func Grids(samples []Sample) ([]ChipGrid, error) {
+	chips := make(map[string]*ChipGrid)
+	positions := make(chipPositions)
+	for i := range samples {
+		sample := &samples[i]
+		if sample.SentrixID == "" {
+			continue
+		}
+		g, ok := chips[sample.SentrixID]
+		if !ok {
+			layout, ok := LayoutFor(sample.BeadChipVersion)
+			if !ok {
+				return nil, fmt.Errorf("chip %s: unknown BeadChip version '%s'", sample.SentrixID, sample.BeadChipVersion)
+			}
+			g = &ChipGrid{
+				SentrixID:       sample.SentrixID,
+				BeadChipVersion: sample.BeadChipVersion,
+				Layout:          layout,
+				Cells:           make([][]*Sample, layout.Rows),
+			}
+			for r := range g.Cells {
+				g.Cells[r] = make([]*Sample, layout.Cols)
+			}
+			chips[sample.SentrixID] = g
+		}
+		if sample.BeadChipVersion != g.BeadChipVersion {
+			return nil, fmt.Errorf("chip %s: has BeadChip versions '%s' and '%s'", g.SentrixID, g.BeadChipVersion, sample.BeadChipVersion)
+		}
+		row, col, err := ParsePosition(sample.SentrixPosition)
+		if err != nil {
+			return nil, fmt.Errorf("sample %s: %w", sample.UIN, err)
+		}
+		if !g.Layout.Contains(row, col) {
+			return nil, fmt.Errorf("sample %s: position %s does not exist on a %s chip", sample.UIN, sample.SentrixPosition, g.Layout.Name)
+		}
+		if err := positions.add(*sample); err != nil {
+			return nil, err
+		}
+		g.Cells[row-1][col-1] = sample
+	}
+	grids := make([]ChipGrid, 0, len(chips))
+	for _, g := range chips {
+		grids = append(grids, *g)
+	}
+	sort.Slice(grids, func(i, j int) bool { return grids[i].SentrixID < grids[j].SentrixID })
+	return grids, nil
+}
+
+// cell returns the text shown for a position in the grid.
+func (g ChipGrid) cell(row, col int) string {
+	if s := g.Cells[row][col]; s != nil {
+		return s.UIN
+	}
+	return "-"
+}
+
+// WriteText renders the grid as an aligned plain text table.
+func (g ChipGrid) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s (%s)\n", g.SentrixID, g.Layout.Name)
+	for c := 0; c < g.Layout.Cols; c++ {
+		fmt.Fprintf(tw, "\tC%02d", c+1)
+	}
+	fmt.Fprintln(tw)
+	for r := 0; r < g.Layout.Rows; r++ {
+		fmt.Fprintf(tw, "R%02d", r+1)
+		for c := 0; c < g.Layout.Cols; c++ {
+			fmt.Fprintf(tw, "\t%s", g.cell(r, c))
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+// WriteGridsText renders each grid as plain text separated by a blank line.
+func WriteGridsText(w io.Writer, grids []ChipGrid) error {
+	for i, g := range grids {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if err := g.WriteText(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteGridsWorkbook saves the grids to a new xlsx workbook with one sheet per
+// chip, named after the SentrixID.
+func WriteGridsWorkbook(fn string, grids []ChipGrid) error {
+	if len(grids) == 0 {
+		return fmt.Errorf("no chips to write")
+	}
+	f := excelize.NewFile()
+	for _, g := range grids {
+		f.NewSheet(g.SentrixID)
+		if err := writeGridSheet(f, g); err != nil {
+			return fmt.Errorf("failed to write chip %s: %w", g.SentrixID, err)
+		}
+	}
+	f.DeleteSheet("Sheet1")
+	f.SetActiveSheet(f.GetSheetIndex(grids[0].SentrixID))
+	return f.SaveAs(fn)
+}
+
+func writeGridSheet(f *excelize.File, g ChipGrid) error {
+	set := func(col, row int, value interface{}) error {
+		axis, err := excelize.CoordinatesToCellName(col, row)
+		if err != nil {
+			return err
+		}
+		return f.SetCellValue(g.SentrixID, axis, value)
+	}
+	if err := set(1, 1, g.SentrixID+" ("+g.Layout.Name+")"); err != nil {
+		return err
+	}
+	for c := 0; c < g.Layout.Cols; c++ {
+		if err := set(c+2, 2, fmt.Sprintf("C%02d", c+1)); err != nil {
+			return err
+		}
+	}
+	for r := 0; r < g.Layout.Rows; r++ {
+		if err := set(1, r+3, fmt.Sprintf("R%02d", r+1)); err != nil {
+			return err
+		}
+		for c := 0; c < g.Layout.Cols; c++ {
+			if g.Cells[r][c] == nil {
+				continue
+			}
+			if err := set(c+2, r+3, g.Cells[r][c].UIN); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package arraylog
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IDATPair is the Red/Grn IDAT pair for a sample.
+type IDATPair struct {
+	Sample Sample
+	Red    string
+	Grn    string
+}
+
+// MissingIDAT records a sample with one or both of its IDATs missing.
+type MissingIDAT struct {
+	Sample  Sample
+	Missing []string
+}
+
+// IDATReport is the result of ResolveIDATs.
+type IDATReport struct {
+	Found   []IDATPair
+	Missing []MissingIDAT
+	// Unexpected lists IDAT files in the chip directories that do not belong
+	// to any of the samples.
+	Unexpected []string
+}
+
+// ResolveIDATs finds the IDATs for each sample below root using the layout
+// iScan writes: <root>/<SentrixID>/<SentrixID>_<Position>_{Red,Grn}.idat.
+// Samples without a Sentrix ID or position are reported as missing both
+// files.
+func ResolveIDATs(root string, samples []Sample) (IDATReport, error) {
+	report := IDATReport{}
+	expected := make(map[string]bool)
+	chips := make(map[string]bool)
+	for _, sample := range samples {
+		if sample.SentrixID == "" || sample.SentrixPosition == "" {
+			report.Missing = append(report.Missing, MissingIDAT{sample, []string{"Red", "Grn"}})
+			continue
+		}
+		chips[sample.SentrixID] = true
+		pair := IDATPair{Sample: sample}
+		missing := []string{}
+		for _, colour := range []string{"Red", "Grn"} {
+			fn := filepath.Join(root, sample.SentrixID, idatName(sample, colour))
+			expected[fn] = true
+			ok, err := fileExists(fn)
+			if err != nil {
+				return IDATReport{}, err
+			}
+			if !ok {
+				missing = append(missing, colour)
+				continue
+			}
+			if colour == "Red" {
+				pair.Red = fn
+			} else {
+				pair.Grn = fn
+			}
+		}
+		if len(missing) > 0 {
+			report.Missing = append(report.Missing, MissingIDAT{sample, missing})
+		} else {
+			report.Found = append(report.Found, pair)
+		}
+	}
+	for chip := range chips {
+		fs, err := ioutil.ReadDir(filepath.Join(root, chip))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return IDATReport{}, err
+		}
+		for _, f := range fs {
+			fn := filepath.Join(root, chip, f.Name())
+			if !f.IsDir() && strings.HasSuffix(f.Name(), ".idat") && !expected[fn] {
+				report.Unexpected = append(report.Unexpected, fn)
+			}
+		}
+	}
+	sort.Strings(report.Unexpected)
+	return report, nil
+}
+
+func idatName(s Sample, colour string) string {
+	return fmt.Sprintf("%s_%s_%s.idat", s.SentrixID, s.SentrixPosition, colour)
+}
+
+func fileExists(fn string) (bool, error) {
+	info, err := os.Stat(fn)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !info.IsDir(), nil
+}
+
+// WriteIDATManifest writes the pairs as a CSV with uin, illumina_id, red and
+// grn columns.
+func WriteIDATManifest(w io.Writer, pairs []IDATPair) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"uin", "illumina_id", "red", "grn"}); err != nil {
+		return err
+	}
+	for _, p := range pairs {
+		if err := cw.Write([]string{p.Sample.UIN, p.Sample.IlluminaID, p.Red, p.Grn}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,130 @@
+package arraylog
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Merged is the combined view of the "IFM Queue" and "Sample Log" sheets keyed
+// by UIN.
+type Merged struct {
+	Samples map[string]Sample
+	// OnlyInIFMQueue lists the UINs that do not appear in the "Sample Log"
+	// sheet.
+	OnlyInIFMQueue []string
+	// OnlyInSampleLog lists the UINs that do not appear in the "IFM Queue"
+	// sheet.
+	OnlyInSampleLog []string
+	// Duplicates holds every row of UINs that appear more than once in the
+	// "IFM Queue" sheet, such as re-genotyped samples, in sheet order.
+	// Samples holds the last of them.
+	Duplicates map[string][]Sample
+	// SampleLogDuplicates holds every row of UINs that appear more than once
+	// in the "Sample Log" sheet, in sheet order. The clinical fields of the
+	// last of them are merged into Samples.
+	SampleLogDuplicates map[string][]Sample
+}
+
+// Merge combines samples scanned from the "IFM Queue" and "Sample Log" sheets.
+// Genotyping fields come from the "IFM Queue" sheet. Clinical fields come from
+// the "Sample Log" sheet and are filled in from the "IFM Queue" sheet when they
+// are blank. Samples found in only one sheet are included as they are and
+// their UINs are reported, as are UINs on more than one row of either sheet.
+func Merge(ifmQueue, sampleLog []Sample) Merged {
+	m := Merged{
+		Samples:             make(map[string]Sample),
+		Duplicates:          duplicates(ifmQueue),
+		SampleLogDuplicates: duplicates(sampleLog),
+	}
+	clinical := make(map[string]Sample)
+	for _, sample := range sampleLog {
+		clinical[sample.UIN] = sample
+	}
+	for _, sample := range ifmQueue {
+		if c, ok := clinical[sample.UIN]; ok {
+			sample = mergeClinical(c, sample)
+		} else if _, seen := m.Samples[sample.UIN]; !seen {
+			m.OnlyInIFMQueue = append(m.OnlyInIFMQueue, sample.UIN)
+		}
+		m.Samples[sample.UIN] = sample
+	}
+	for uin, sample := range clinical {
+		if _, ok := m.Samples[uin]; !ok {
+			m.Samples[uin] = sample
+			m.OnlyInSampleLog = append(m.OnlyInSampleLog, uin)
+		}
+	}
+	sort.Strings(m.OnlyInIFMQueue)
+	sort.Strings(m.OnlyInSampleLog)
+	return m
+}
+
+// duplicates returns every row of the UINs that appear more than once, keyed
+// by UIN.
+func duplicates(samples []Sample) map[string][]Sample {
+	rows := make(map[string][]Sample)
+	for _, sample := range samples {
+		rows[sample.UIN] = append(rows[sample.UIN], sample)
+	}
+	dups := make(map[string][]Sample)
+	for uin, xs := range rows {
+		if len(xs) > 1 {
+			dups[uin] = xs
+		}
+	}
+	return dups
+}
+
+// mergeClinical copies the clinical fields from c into sample unless they are
+// blank in c.
+func mergeClinical(c, sample Sample) Sample {
+	pairs := []struct {
+		dst *string
+		src string
+	}{
+		{&sample.SubjectID, c.SubjectID},
+		{&sample.ProjectID, c.ProjectID},
+		{&sample.URN, c.URN},
+		{&sample.PatientName, c.PatientName},
+		{&sample.DOB, c.DOB},
+		{&sample.Gender, c.Gender},
+		{&sample.SampleType, c.SampleType},
+		{&sample.Disease, c.Disease},
+		{&sample.RequestingClinician, c.RequestingClinician},
+		{&sample.ReceiptDate, c.ReceiptDate},
+	}
+	for _, p := range pairs {
+		if p.src != "" {
+			*p.dst = p.src
+		}
+	}
+	return sample
+}
+
+// NewMerged scans both the "IFM Queue" and "Sample Log" sheets of the array log
+// and merges them.
+func NewMerged(fn string, opts Options) (Merged, error) {
+	ifmQueue, err := scanAll(NewScanner(fn, opts))
+	if err != nil {
+		return Merged{}, fmt.Errorf("failed to scan %s sheet: %w", ifmQueueSheet, err)
+	}
+	sampleLog, err := scanAll(NewSampleLogScanner(fn, opts))
+	if err != nil {
+		return Merged{}, fmt.Errorf("failed to scan %s sheet: %w", sampleLogSheet, err)
+	}
+	return Merge(ifmQueue, sampleLog), nil
+}
+
+func scanAll(scanner *Scanner, err error) ([]Sample, error) {
+	if err != nil {
+		return nil, fmt.Errorf("unable to create log scanner: %w", err)
+	}
+	samples := []Sample{}
+	for scanner.Scan() {
+		samples = append(samples, scanner.Sample())
+	}
+	if err := scanner.Error(); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
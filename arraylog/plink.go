@@ -0,0 +1,108 @@
+package arraylog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// PLINK sex codes.
+const (
+	SexUnknown = 0
+	SexMale    = 1
+	SexFemale  = 2
+)
+
+// PlinkOptions controls the PLINK files written by WriteFam and
+// WriteUpdateIDs.
+type PlinkOptions struct {
+	// Sex returns the PLINK sex code for a sample. When nil every sample is
+	// written with SexUnknown.
+	Sex func(Sample) int
+	// OriginalFID is the family ID the samples have straight out of calling.
+	// When empty the IlluminaID is used, as for the individual ID.
+	OriginalFID string
+}
+
+// SexFromGender is a PlinkOptions.Sex source that uses the Gender column of the
+// array log.
+func SexFromGender(s Sample) int {
+	switch strings.ToUpper(s.Gender) {
+	case "MALE", "M":
+		return SexMale
+	case "FEMALE", "F":
+		return SexFemale
+	default:
+		return SexUnknown
+	}
+}
+
+// plinkSamples returns the samples sorted by IlluminaID so that the output is
+// the same from run to run, and checks the IDs can be written to a
+// whitespace-delimited file.
+func plinkSamples(samples []Sample) ([]Sample, error) {
+	xs := make([]Sample, len(samples))
+	copy(xs, samples)
+	sort.SliceStable(xs, func(i, j int) bool { return xs[i].IlluminaID < xs[j].IlluminaID })
+	positions := make(chipPositions)
+	for _, s := range xs {
+		if s.SentrixID == "" || s.SentrixPosition == "" {
+			return nil, fmt.Errorf("sample %s: missing Sentrix ID or position", s.UIN)
+		}
+		if err := positions.add(s); err != nil {
+			return nil, err
+		}
+		for _, id := range []string{s.IlluminaID, s.UIN, familyID(s)} {
+			if strings.ContainsAny(id, " \t") {
+				return nil, fmt.Errorf("sample %s: ID '%s' contains whitespace", s.UIN, id)
+			}
+		}
+	}
+	return xs, nil
+}
+
+// familyID is the SubjectID, or the UIN if the subject is not known.
+func familyID(s Sample) string {
+	if s.SubjectID != "" {
+		return s.SubjectID
+	}
+	return s.UIN
+}
+
+// WriteFam writes a PLINK .fam file with the SubjectID as family ID and the
+// UIN as individual ID. Parents and phenotype are unknown.
+func WriteFam(w io.Writer, samples []Sample, opts PlinkOptions) error {
+	xs, err := plinkSamples(samples)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	for _, s := range xs {
+		sex := SexUnknown
+		if opts.Sex != nil {
+			sex = opts.Sex(s)
+		}
+		fmt.Fprintf(bw, "%s %s 0 0 %d -9\n", familyID(s), s.UIN, sex)
+	}
+	return bw.Flush()
+}
+
+// WriteUpdateIDs writes a file for plink --update-ids that maps the
+// IlluminaID of each sample to its SubjectID and UIN.
+func WriteUpdateIDs(w io.Writer, samples []Sample, opts PlinkOptions) error {
+	xs, err := plinkSamples(samples)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	for _, s := range xs {
+		fid := opts.OriginalFID
+		if fid == "" {
+			fid = s.IlluminaID
+		}
+		fmt.Fprintf(bw, "%s %s %s %s\n", fid, s.IlluminaID, familyID(s), s.UIN)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,162 @@
+package arraylog
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultMinCallRate is the call rate below which a sample fails QC unless
+// another threshold is given.
+const DefaultMinCallRate = 0.98
+
+// ErrNoValue is returned when a numeric cell is blank or "NA".
+var ErrNoValue = errors.New("no value")
+
+// ParseCallRate parses a call rate as it appears in the log. Percentages such
+// as "99.5%" or "99.5" and fractions such as "0.995" are all returned as a
+// fraction. Blank and "NA" cells return ErrNoValue.
+func ParseCallRate(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "NA") {
+		return 0, ErrNoValue
+	}
+	percent := strings.HasSuffix(s, "%")
+	s = strings.TrimSpace(strings.TrimSuffix(s, "%"))
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse call rate '%s': %w", s, err)
+	}
+	if percent || n > 1 {
+		n /= 100
+	}
+	if n < 0 || n > 1 {
+		return 0, fmt.Errorf("call rate out of range: %s", s)
+	}
+	return n, nil
+}
+
+// CallRateValue returns GenotypingCallRate as a fraction.
+func (s Sample) CallRateValue() (float64, error) {
+	return ParseCallRate(s.GenotypingCallRate)
+}
+
+// NumInAssayValue returns NumInAssay as an integer. Blank and "NA" cells return
+// ErrNoValue.
+func (s Sample) NumInAssayValue() (int, error) {
+	v := strings.TrimSpace(s.NumInAssay)
+	if v == "" || strings.EqualFold(v, "NA") {
+		return 0, ErrNoValue
+	}
+	// Excel sometimes stores whole numbers as "12.0".
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n != float64(int(n)) {
+		return 0, fmt.Errorf("unable to parse # in assay '%s'", v)
+	}
+	return int(n), nil
+}
+
+// CallRateSummary summarises the call rates of a group of samples.
+type CallRateSummary struct {
+	Key string
+	// N is the number of samples in the group, including those without a
+	// call rate or with one that can not be parsed.
+	N       int
+	Missing int
+	Invalid int
+	Failed  int
+	Mean    float64
+	Min     float64
+	Max     float64
+}
+
+// QCResult is the result of EvaluateQC.
+type QCResult struct {
+	MinCallRate float64
+	// Failed lists the samples with a call rate below MinCallRate.
+	Failed []Sample
+	// Missing lists the samples without a call rate.
+	Missing []Sample
+	// Invalid lists the samples whose call rate can not be parsed.
+	Invalid []Sample
+	ByBatch []CallRateSummary
+	ByChip  []CallRateSummary
+}
+
+// EvaluateQC flags the samples with a call rate below minCallRate and
+// summarises call rates per BeadChipBatch and per SentrixID. A negative
+// minCallRate uses DefaultMinCallRate and zero fails no sample. Call rates
+// that can not be parsed are reported in Invalid rather than stopping the
+// evaluation. Summaries are sorted by key.
+func EvaluateQC(samples []Sample, minCallRate float64) QCResult {
+	if minCallRate < 0 {
+		minCallRate = DefaultMinCallRate
+	}
+	result := QCResult{MinCallRate: minCallRate}
+	batches := make(map[string]*callRateGroup)
+	chips := make(map[string]*callRateGroup)
+	for _, sample := range samples {
+		rate, err := sample.CallRateValue()
+		failed := err == nil && rate < minCallRate
+		switch {
+		case errors.Is(err, ErrNoValue):
+			result.Missing = append(result.Missing, sample)
+		case err != nil:
+			result.Invalid = append(result.Invalid, sample)
+		case failed:
+			result.Failed = append(result.Failed, sample)
+		}
+		addToGroup(batches, sample.BeadChipBatch, rate, err, failed)
+		addToGroup(chips, sample.SentrixID, rate, err, failed)
+	}
+	result.ByBatch = summarise(batches)
+	result.ByChip = summarise(chips)
+	return result
+}
+
+type callRateGroup struct {
+	summary CallRateSummary
+	total   float64
+}
+
+// addToGroup adds a sample's call rate to its group. err is the error from
+// parsing the call rate, if any.
+func addToGroup(groups map[string]*callRateGroup, key string, rate float64, err error, failed bool) {
+	g, ok := groups[key]
+	if !ok {
+		g = &callRateGroup{summary: CallRateSummary{Key: key}}
+		groups[key] = g
+	}
+	g.summary.N++
+	switch {
+	case errors.Is(err, ErrNoValue):
+		g.summary.Missing++
+		return
+	case err != nil:
+		g.summary.Invalid++
+		return
+	}
+	if failed {
+		g.summary.Failed++
+	}
+	n := g.summary.N - g.summary.Missing - g.summary.Invalid
+	if n == 1 || rate < g.summary.Min {
+		g.summary.Min = rate
+	}
+	if n == 1 || rate > g.summary.Max {
+		g.summary.Max = rate
+	}
+	g.total += rate
+	g.summary.Mean = g.total / float64(n)
+}
+
+func summarise(groups map[string]*callRateGroup) []CallRateSummary {
+	xs := make([]CallRateSummary, 0, len(groups))
+	for _, g := range groups {
+		xs = append(xs, g.summary)
+	}
+	sort.Slice(xs, func(i, j int) bool { return xs[i].Key < xs[j].Key })
+	return xs
+}
@@ -0,0 +1,61 @@
+package arraylog
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SampleSheetOptions controls the [Header] section of an array sample sheet
+// and which samples are written.
+type SampleSheetOptions struct {
+	InvestigatorName string
+	ProjectName      string
+	ExperimentName   string
+	// Date defaults to today.
+	Date time.Time
+	// Manifest, if set, is written to a [Manifests] section as manifest A.
+	Manifest string
+	// DropExcluded removes samples marked as excluded.
+	DropExcluded bool
+}
+
+// WriteSampleSheet writes samples as an Illumina array sample sheet that can be
+// imported by GenomeStudio and passed to iaap-cli gencall. Sample_ID is the
+// UIN and Sample_Group is the ProjectID.
+func WriteSampleSheet(w io.Writer, samples []Sample, opts SampleSheetOptions) error {
+	if opts.DropExcluded {
+		samples, _ = Partition(samples)
+	}
+	date := opts.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	records := [][]string{
+		{"[Header]"},
+		{"Investigator Name", opts.InvestigatorName},
+		{"Project Name", opts.ProjectName},
+		{"Experiment Name", opts.ExperimentName},
+		{"Date", date.Format("1/2/2006")},
+	}
+	if opts.Manifest != "" {
+		records = append(records, []string{"[Manifests]"}, []string{"A", opts.Manifest})
+	}
+	records = append(records,
+		[]string{"[Data]"},
+		[]string{"Sample_ID", "SentrixBarcode_A", "SentrixPosition_A", "Sample_Group"},
+	)
+	for _, sample := range samples {
+		if sample.SentrixID == "" || sample.SentrixPosition == "" {
+			return fmt.Errorf("sample %s: missing Sentrix ID or position", sample.UIN)
+		}
+		records = append(records, []string{sample.UIN, sample.SentrixID, sample.SentrixPosition, sample.ProjectID})
+	}
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+	if err := cw.WriteAll(records); err != nil {
+		return fmt.Errorf("failed to write sample sheet: %w", err)
+	}
+	return nil
+}
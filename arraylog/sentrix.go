@@ -0,0 +1,152 @@
+package arraylog
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ChipLayout describes the sample positions on a BeadChip. Positions run from
+// R01C01 to R<Rows>C<Cols>.
+type ChipLayout struct {
+	Name string
+	Rows int
+	Cols int
+}
+
+// Samples returns the number of samples the chip holds.
+func (l ChipLayout) Samples() int {
+	return l.Rows * l.Cols
+}
+
+// Contains reports whether the position exists on the chip.
+func (l ChipLayout) Contains(row, col int) bool {
+	return row >= 1 && row <= l.Rows && col >= 1 && col <= l.Cols
+}
+
+// chipLayouts maps fragments of the "Beadchip version" column onto a layout.
+// The first matching entry wins, so more specific fragments must come first.
+var chipLayouts = []struct {
+	match  []string
+	layout ChipLayout
+}{
+	{[]string{"gsa", "global screening"}, ChipLayout{Name: "GSA 24-sample", Rows: 12, Cols: 2}},
+	{[]string{"gda", "global diversity"}, ChipLayout{Name: "GDA 8-sample", Rows: 8, Cols: 1}},
+	{[]string{"epic", "methylation"}, ChipLayout{Name: "EPIC 8-sample", Rows: 8, Cols: 1}},
+	{[]string{"omniexpressexome", "omniexpress exome"}, ChipLayout{Name: "OmniExpressExome 8-sample", Rows: 8, Cols: 1}},
+	{[]string{"omniexpress"}, ChipLayout{Name: "OmniExpress 24-sample", Rows: 12, Cols: 2}},
+	{[]string{"omni2.5", "omni 2.5", "omni25"}, ChipLayout{Name: "Omni2.5 8-sample", Rows: 8, Cols: 1}},
+}
+
+// sampleCountPattern matches the sample count in a product name such as
+// "InfiniumOmniExpressExome-8v1-6_A1" or "GSA-24v3-0".
+var sampleCountPattern = regexp.MustCompile(`-(\d+)(?:v|_|\s|$)`)
+
+// chipShapes maps the sample count of a chip onto its rows and columns.
+var chipShapes = map[int][2]int{
+	8:  {8, 1},
+	12: {12, 1},
+	24: {12, 2},
+}
+
+// LayoutFor returns the chip layout for a "Beadchip version" value. A sample
+// count in the value, such as the 8 in "OmniExpressExome-8v1-6", takes
+// precedence over the usual layout of the chip.
+func LayoutFor(beadChipVersion string) (ChipLayout, bool) {
+	v := strings.ToLower(beadChipVersion)
+	for _, l := range chipLayouts {
+		for _, m := range l.match {
+			if strings.Contains(v, m) {
+				return withSampleCount(l.layout, v), true
+			}
+		}
+	}
+	return ChipLayout{}, false
+}
+
+func withSampleCount(layout ChipLayout, beadChipVersion string) ChipLayout {
+	m := sampleCountPattern.FindStringSubmatch(beadChipVersion)
+	if m == nil {
+		return layout
+	}
+	n, _ := strconv.Atoi(m[1])
+	shape, ok := chipShapes[n]
+	if !ok || n == layout.Samples() {
+		return layout
+	}
+	name := strings.TrimSuffix(layout.Name, fmt.Sprintf(" %d-sample", layout.Samples()))
+	return ChipLayout{Name: fmt.Sprintf("%s %d-sample", name, n), Rows: shape[0], Cols: shape[1]}
+}
+
+var (
+	sentrixIDPattern       = regexp.MustCompile(`^\d{12}$`)
+	sentrixPositionPattern = regexp.MustCompile(`^R(\d{2})C(\d{2})$`)
+)
+
+// ParsePosition parses a Sentrix position of the form RxxCxx.
+func ParsePosition(pos string) (row, col int, err error) {
+	m := sentrixPositionPattern.FindStringSubmatch(pos)
+	if m == nil {
+		return 0, 0, fmt.Errorf("invalid Sentrix position '%s': expected RxxCxx", pos)
+	}
+	row, _ = strconv.Atoi(m[1])
+	col, _ = strconv.Atoi(m[2])
+	return row, col, nil
+}
+
+// ValidateSentrix checks that the Sentrix ID is a 12-digit barcode and that the
+// position has the RxxCxx form and exists on the chip for the sample's
+// BeadChipVersion.
+func (s Sample) ValidateSentrix() error {
+	if !sentrixIDPattern.MatchString(s.SentrixID) {
+		return fmt.Errorf("sample %s: invalid Sentrix ID '%s': expected a 12-digit barcode", s.UIN, s.SentrixID)
+	}
+	row, col, err := ParsePosition(s.SentrixPosition)
+	if err != nil {
+		return fmt.Errorf("sample %s: %w", s.UIN, err)
+	}
+	layout, ok := LayoutFor(s.BeadChipVersion)
+	if !ok {
+		return fmt.Errorf("sample %s: unknown BeadChip version '%s'", s.UIN, s.BeadChipVersion)
+	}
+	if !layout.Contains(row, col) {
+		return fmt.Errorf("sample %s: position %s does not exist on a %s chip", s.UIN, s.SentrixPosition, layout.Name)
+	}
+	return nil
+}
+
+// ValidateSentrixAll checks each sample with Sample.ValidateSentrix and reports
+// samples that claim the same chip position. Samples that have not been put on
+// a chip yet, i.e. with neither a Sentrix ID nor a position, are skipped.
+func ValidateSentrixAll(samples []Sample) []error {
+	errs := []error{}
+	positions := make(chipPositions)
+	for _, sample := range samples {
+		if sample.SentrixID == "" && sample.SentrixPosition == "" {
+			continue
+		}
+		if err := sample.ValidateSentrix(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := positions.add(sample); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// chipPositions records the UIN of the sample at each IlluminaID, i.e. each
+// Sentrix ID and position.
+type chipPositions map[string]string
+
+// add records the sample's position. It is an error if another sample is
+// already there.
+func (p chipPositions) add(s Sample) error {
+	if uin, ok := p[s.IlluminaID]; ok {
+		return fmt.Errorf("samples %s and %s are both at %s", uin, s.UIN, s.IlluminaID)
+	}
+	p[s.IlluminaID] = s.UIN
+	return nil
+}
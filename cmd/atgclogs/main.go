@@ -0,0 +1,43 @@
+// Command atgclogs maintains the files that go alongside the ATGC logs.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jje42/atgclogs/runsheet"
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	rootCmd := &cobra.Command{
+		Use:   "atgclogs",
+		Short: "Tools for the ATGC internal logs",
+	}
+	rootCmd.AddCommand(lookupCmd())
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func lookupCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "lookup <runsheet dir>",
+		Short: "Rebuild lookup.csv in a runsheet directory",
+		Long: `Rebuild the run number to runsheet lookup.csv in a runsheet directory.
+Only runsheets that changed since the last update are parsed.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			update, err := runsheet.UpdateLookup(args[0])
+			if err != nil {
+				return err
+			}
+			for _, w := range update.Warnings {
+				fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+			}
+			fmt.Printf("%d entries: %d added, %d updated, %d removed\n",
+				len(update.Entries), len(update.Added), len(update.Updated), len(update.Removed))
+			return nil
+		},
+	}
+}
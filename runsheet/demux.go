@@ -0,0 +1,387 @@
+package runsheet
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default thresholds for JoinDemuxStats.
+const (
+	DefaultMinReads        = 100000
+	DefaultMinUnknownReads = 100000
+)
+
+// DemuxStat is the demultiplexing result for one sample in one lane.
+type DemuxStat struct {
+	Lane              int
+	SampleID          string
+	Index             string
+	Reads             int64
+	PerfectIndexReads int64
+	// PercentOfLane and PercentPerfectIndex are percentages, not fractions.
+	PercentOfLane       float64
+	PercentPerfectIndex float64
+}
+
+// UnknownBarcode is an index pair that did not match any sample.
+type UnknownBarcode struct {
+	Lane   int
+	Index  string
+	Index2 string
+	Reads  int64
+}
+
+// DemuxStats holds the demultiplexing results for a run.
+type DemuxStats struct {
+	// Demultiplexer is the software that wrote the statistics.
+	Demultiplexer Demultiplexer
+	Samples       []DemuxStat
+	Unknown       []UnknownBarcode
+}
+
+// LoadDemuxStats reads the demultiplexing statistics in an output directory,
+// either BCL Convert's Reports directory or bcl2fastq's Stats/Stats.json.
+func LoadDemuxStats(dir string) (DemuxStats, error) {
+	reports := filepath.Join(dir, "Reports")
+	if _, err := os.Stat(filepath.Join(reports, "Demultiplex_Stats.csv")); err == nil {
+		return ReadBCLConvertStats(reports)
+	}
+	fn := filepath.Join(dir, "Stats", "Stats.json")
+	if _, err := os.Stat(fn); err == nil {
+		return ReadBcl2fastqStats(fn)
+	}
+	return DemuxStats{}, fmt.Errorf("no demultiplexing statistics found in %s", dir)
+}
+
+// ReadBCLConvertStats reads Demultiplex_Stats.csv and, if present,
+// Top_Unknown_Barcodes.csv from a BCL Convert Reports directory.
+func ReadBCLConvertStats(reportsDir string) (DemuxStats, error) {
+	stats := DemuxStats{Demultiplexer: BCLConvert}
+	records, err := readCSVRecords(filepath.Join(reportsDir, "Demultiplex_Stats.csv"))
+	if err != nil {
+		return DemuxStats{}, err
+	}
+	laneTotals := make(map[int]int64)
+	for _, rec := range records {
+		lane, err := rec.int("Lane")
+		if err != nil {
+			return DemuxStats{}, err
+		}
+		reads, err := rec.int64("# Reads")
+		if err != nil {
+			return DemuxStats{}, err
+		}
+		perfect, err := rec.int64("# Perfect Index Reads")
+		if err != nil {
+			return DemuxStats{}, err
+		}
+		laneTotals[lane] += reads
+		// Undetermined reads count towards the lane total only.
+		if rec.get("SampleID") == "Undetermined" {
+			continue
+		}
+		stats.Samples = append(stats.Samples, DemuxStat{
+			Lane:              lane,
+			SampleID:          rec.get("SampleID"),
+			Index:             rec.get("Index"),
+			Reads:             reads,
+			PerfectIndexReads: perfect,
+		})
+	}
+	setPercentages(stats.Samples, laneTotals)
+
+	fn := filepath.Join(reportsDir, "Top_Unknown_Barcodes.csv")
+	if _, err := os.Stat(fn); err != nil {
+		return stats, nil
+	}
+	records, err = readCSVRecords(fn)
+	if err != nil {
+		return DemuxStats{}, err
+	}
+	for _, rec := range records {
+		lane, err := rec.int("Lane")
+		if err != nil {
+			return DemuxStats{}, err
+		}
+		reads, err := rec.int64("# Reads")
+		if err != nil {
+			return DemuxStats{}, err
+		}
+		stats.Unknown = append(stats.Unknown, UnknownBarcode{
+			Lane:   lane,
+			Index:  rec.get("index"),
+			Index2: rec.get("index2"),
+			Reads:  reads,
+		})
+	}
+	return stats, nil
+}
+
+// ReadBcl2fastqStats reads a bcl2fastq Stats.json file.
+func ReadBcl2fastqStats(fn string) (DemuxStats, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return DemuxStats{}, err
+	}
+	defer f.Close()
+	var doc struct {
+		ConversionResults []struct {
+			LaneNumber      int   `json:"LaneNumber"`
+			TotalClustersPF int64 `json:"TotalClustersPF"`
+			DemuxResults    []struct {
+				SampleID     string `json:"SampleId"`
+				NumberReads  int64  `json:"NumberReads"`
+				IndexMetrics []struct {
+					IndexSequence  string           `json:"IndexSequence"`
+					MismatchCounts map[string]int64 `json:"MismatchCounts"`
+				} `json:"IndexMetrics"`
+			} `json:"DemuxResults"`
+			Undetermined struct {
+				NumberReads int64 `json:"NumberReads"`
+			} `json:"Undetermined"`
+		} `json:"ConversionResults"`
+		UnknownBarcodes []struct {
+			Lane     int              `json:"Lane"`
+			Barcodes map[string]int64 `json:"Barcodes"`
+		} `json:"UnknownBarcodes"`
+	}
+	if err := json.NewDecoder(f).Decode(&doc); err != nil {
+		return DemuxStats{}, fmt.Errorf("failed to parse %s: %w", fn, err)
+	}
+	stats := DemuxStats{Demultiplexer: Bcl2fastq}
+	laneTotals := make(map[int]int64)
+	for _, lane := range doc.ConversionResults {
+		total := lane.Undetermined.NumberReads
+		for _, result := range lane.DemuxResults {
+			stat := DemuxStat{
+				Lane:     lane.LaneNumber,
+				SampleID: result.SampleID,
+				Reads:    result.NumberReads,
+			}
+			for _, m := range result.IndexMetrics {
+				stat.Index = strings.Replace(m.IndexSequence, "+", "-", 1)
+				stat.PerfectIndexReads += m.MismatchCounts["0"]
+			}
+			total += result.NumberReads
+			stats.Samples = append(stats.Samples, stat)
+		}
+		if lane.TotalClustersPF > 0 {
+			total = lane.TotalClustersPF
+		}
+		laneTotals[lane.LaneNumber] = total
+	}
+	setPercentages(stats.Samples, laneTotals)
+	for _, lane := range doc.UnknownBarcodes {
+		for barcode, reads := range lane.Barcodes {
+			parts := strings.SplitN(barcode, "+", 2)
+			u := UnknownBarcode{Lane: lane.Lane, Index: parts[0], Reads: reads}
+			if len(parts) == 2 {
+				u.Index2 = parts[1]
+			}
+			stats.Unknown = append(stats.Unknown, u)
+		}
+	}
+	// Barcodes come from a map, so order them as Top_Unknown_Barcodes.csv
+	// does.
+	sort.Slice(stats.Unknown, func(i, j int) bool {
+		a, b := stats.Unknown[i], stats.Unknown[j]
+		if a.Lane != b.Lane {
+			return a.Lane < b.Lane
+		}
+		if a.Reads != b.Reads {
+			return a.Reads > b.Reads
+		}
+		return a.Index+a.Index2 < b.Index+b.Index2
+	})
+	return stats, nil
+}
+
+func setPercentages(stats []DemuxStat, laneTotals map[int]int64) {
+	for i := range stats {
+		s := &stats[i]
+		if total := laneTotals[s.Lane]; total > 0 {
+			s.PercentOfLane = 100 * float64(s.Reads) / float64(total)
+		}
+		if s.Reads > 0 {
+			s.PercentPerfectIndex = 100 * float64(s.PerfectIndexReads) / float64(s.Reads)
+		}
+	}
+}
+
+// csvRecord is a CSV row keyed by the header of the file.
+type csvRecord struct {
+	fn     string
+	header map[string]int
+	row    []string
+}
+
+func (r csvRecord) get(column string) string {
+	if i, ok := r.header[column]; ok && i < len(r.row) {
+		return strings.TrimSpace(r.row[i])
+	}
+	return ""
+}
+
+func (r csvRecord) int64(column string) (int64, error) {
+	v := r.get(column)
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		// Counts are sometimes written as floats, e.g. "1234.0".
+		f, ferr := strconv.ParseFloat(v, 64)
+		if ferr != nil {
+			return 0, fmt.Errorf("%s: invalid '%s' value '%s'", r.fn, column, v)
+		}
+		n = int64(f)
+	}
+	return n, nil
+}
+
+func (r csvRecord) int(column string) (int, error) {
+	n, err := r.int64(column)
+	return int(n), err
+}
+
+func readCSVRecords(fn string) ([]csvRecord, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	header := make(map[string]int)
+	records := []csvRecord{}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", fn, err)
+		}
+		if len(header) == 0 {
+			for i, col := range row {
+				header[strings.TrimSpace(col)] = i
+			}
+			continue
+		}
+		records = append(records, csvRecord{fn, header, row})
+	}
+	return records, nil
+}
+
+// DemuxOptions controls the thresholds used by JoinDemuxStats. Zero values use
+// DefaultMinReads and DefaultMinUnknownReads.
+type DemuxOptions struct {
+	MinReads        int64
+	MinUnknownReads int64
+}
+
+// SampleDemux is a runsheet sample joined to its demultiplexing result in one
+// lane.
+type SampleDemux struct {
+	Sample Sample
+	Lane   int
+	Stat   DemuxStat
+	// Found is false when the sample is not in the statistics at all.
+	Found    bool
+	LowReads bool
+}
+
+// DropoutSuspect is an unknown barcode that matches a sample's indices with
+// the i5 in the other orientation, which usually means the i5 was entered the
+// wrong way round.
+type DropoutSuspect struct {
+	Unknown UnknownBarcode
+	Sample  Sample
+}
+
+// DemuxReport is the result of JoinDemuxStats.
+type DemuxReport struct {
+	Samples  []SampleDemux
+	Suspects []DropoutSuspect
+}
+
+// JoinDemuxStats attaches demultiplexing results to each sample by Lane and
+// Sample_ID. Samples without a lane are joined to every lane they appear in.
+// Samples with fewer than MinReads reads are flagged, as are unknown barcodes
+// with at least MinUnknownReads reads that match a sample with i5 reverse
+// complemented.
+func (r RunSheet) JoinDemuxStats(stats DemuxStats, opts DemuxOptions) (DemuxReport, error) {
+	if opts.MinReads == 0 {
+		opts.MinReads = DefaultMinReads
+	}
+	if opts.MinUnknownReads == 0 {
+		opts.MinUnknownReads = DefaultMinUnknownReads
+	}
+	samples, err := r.ExpandLanes()
+	if err != nil {
+		return DemuxReport{}, err
+	}
+	report := DemuxReport{}
+	for _, s := range samples {
+		lane, _ := strconv.Atoi(s.Lane)
+		found := false
+		for _, stat := range stats.Samples {
+			if stat.SampleID != s.ID || (lane != 0 && stat.Lane != lane) {
+				continue
+			}
+			found = true
+			report.Samples = append(report.Samples, SampleDemux{
+				Sample:   s,
+				Lane:     stat.Lane,
+				Stat:     stat,
+				Found:    true,
+				LowReads: stat.Reads < opts.MinReads,
+			})
+		}
+		if !found {
+			report.Samples = append(report.Samples, SampleDemux{Sample: s, Lane: lane, LowReads: true})
+		}
+	}
+
+	// The i5 orientations that would have been missed by demultiplexing
+	// with the sample sheet the demultiplexer expects. If the orientation is
+	// not known, either is suspect.
+	o, orientationErr := r.SampleSheetOrientation(stats.Demultiplexer)
+	for _, u := range stats.Unknown {
+		if u.Reads < opts.MinUnknownReads || u.Index2 == "" {
+			continue
+		}
+		for _, s := range samples {
+			lane, _ := strconv.Atoi(s.Lane)
+			if lane != 0 && lane != u.Lane {
+				continue
+			}
+			if !sameIndex(u.Index, s.Index) {
+				continue
+			}
+			suspect := false
+			switch {
+			case orientationErr != nil:
+				suspect = sameIndex(u.Index2, s.Index2) || sameIndex(u.Index2, ReverseComplement(s.Index2))
+			case o == OrientationReverseComplement:
+				suspect = sameIndex(u.Index2, s.Index2)
+			default:
+				suspect = sameIndex(u.Index2, ReverseComplement(s.Index2))
+			}
+			if suspect {
+				report.Suspects = append(report.Suspects, DropoutSuspect{Unknown: u, Sample: s})
+			}
+		}
+	}
+	return report, nil
+}
+
+// sameIndex reports whether two indices agree over their shared prefix.
+func sameIndex(a, b string) bool {
+	d, n := prefixHamming(a, b)
+	return n > 0 && d == 0
+}
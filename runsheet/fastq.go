@@ -0,0 +1,170 @@
+package runsheet
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var fastqPattern = regexp.MustCompile(`^(.+)_S(\d+)_L(\d{3})_([RI][12])_001\.fastq\.gz$`)
+
+// FastqSet is the FASTQ files for one sample in one lane. Reads that were not
+// found are empty.
+type FastqSet struct {
+	Sample Sample
+	Lane   int
+	// Number is the S<n> number the demultiplexer gave the sample.
+	Number int
+	R1     string
+	R2     string
+	I1     string
+	I2     string
+}
+
+// FastqReport is the result of ResolveFastqs.
+type FastqReport struct {
+	Sets []FastqSet
+	// Missing lists the expected files that were not found. The sample
+	// number is shown as S* when it is not known.
+	Missing []string
+	// Unmatched lists the FASTQs that do not belong to any sample.
+	// Undetermined reads are not included.
+	Unmatched []string
+}
+
+// FastqOptions controls which reads ResolveFastqs expects.
+type FastqOptions struct {
+	// IndexReads expects I1 and I2 FASTQs, as written when the
+	// demultiplexer is asked to create FASTQs for index reads.
+	IndexReads bool
+}
+
+type fastqKey struct {
+	id   string
+	lane int
+}
+
+// ResolveFastqs lists the FASTQs in a demultiplexed output directory, and its
+// sub-directories, for each sample in each lane using the Illumina
+// <SampleID>_S<n>_L00<lane>_<read>_001.fastq.gz naming. Samples without a
+// lane are matched to every lane found for them.
+func (r RunSheet) ResolveFastqs(dir string, opts FastqOptions) (FastqReport, error) {
+	found := make(map[fastqKey]*FastqSet)
+	files := make(map[fastqKey][]string)
+	numbers := make(map[string]int)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		m := fastqPattern.FindStringSubmatch(info.Name())
+		if m == nil || m[1] == "Undetermined" {
+			return nil
+		}
+		n, _ := strconv.Atoi(m[2])
+		lane, _ := strconv.Atoi(m[3])
+		key := fastqKey{m[1], lane}
+		numbers[m[1]] = n
+		set, ok := found[key]
+		if !ok {
+			set = &FastqSet{Lane: lane, Number: n}
+			found[key] = set
+		}
+		switch m[4] {
+		case "R1":
+			set.R1 = path
+		case "R2":
+			set.R2 = path
+		case "I1":
+			set.I1 = path
+		case "I2":
+			set.I2 = path
+		}
+		files[key] = append(files[key], path)
+		return nil
+	})
+	if err != nil {
+		return FastqReport{}, err
+	}
+
+	samples, err := r.ExpandLanes()
+	if err != nil {
+		return FastqReport{}, err
+	}
+	h := r.Header
+	expected := []string{"R1"}
+	if h.Read2Cycles > 0 {
+		expected = append(expected, "R2")
+	}
+	if opts.IndexReads && h.I7IndexReadCycles > 0 {
+		expected = append(expected, "I1")
+	}
+	if opts.IndexReads && h.I5IndexReadCycles > 0 {
+		expected = append(expected, "I2")
+	}
+
+	report := FastqReport{}
+	used := make(map[fastqKey]bool)
+	for _, s := range samples {
+		keys := []fastqKey{}
+		if s.Lane == "" {
+			for key := range found {
+				if key.id == s.ID {
+					keys = append(keys, key)
+				}
+			}
+			sort.Slice(keys, func(i, j int) bool { return keys[i].lane < keys[j].lane })
+			if len(keys) == 0 {
+				report.Missing = append(report.Missing, fmt.Sprintf("%s_S*_L*_R1_001.fastq.gz", s.ID))
+				continue
+			}
+		} else {
+			lane, _ := strconv.Atoi(s.Lane)
+			keys = append(keys, fastqKey{s.ID, lane})
+		}
+		for _, key := range keys {
+			set := FastqSet{Lane: key.lane}
+			if f, ok := found[key]; ok {
+				set = *f
+				used[key] = true
+			}
+			set.Sample = s
+			number := "*"
+			if n, ok := numbers[s.ID]; ok {
+				number = strconv.Itoa(n)
+			}
+			for _, read := range expected {
+				if set.read(read) == "" {
+					report.Missing = append(report.Missing, fmt.Sprintf("%s_S%s_L%03d_%s_001.fastq.gz", s.ID, number, key.lane, read))
+				}
+			}
+			report.Sets = append(report.Sets, set)
+		}
+	}
+	for key, paths := range files {
+		if !used[key] {
+			report.Unmatched = append(report.Unmatched, paths...)
+		}
+	}
+	sort.Strings(report.Unmatched)
+	return report, nil
+}
+
+func (s FastqSet) read(read string) string {
+	switch read {
+	case "R1":
+		return s.R1
+	case "R2":
+		return s.R2
+	case "I1":
+		return s.I1
+	case "I2":
+		return s.I2
+	}
+	return ""
+}
@@ -0,0 +1,167 @@
+package runsheet
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IndexCollision is a pair of samples in the same lane whose Index and Index2
+// sequences are too close for the demultiplexer to tell apart.
+type IndexCollision struct {
+	Lane string
+	A    Sample
+	B    Sample
+	// IndexDistance and Index2Distance are the Hamming distances between the
+	// two samples' Index and Index2.
+	IndexDistance  int
+	Index2Distance int
+	// Compared is the number of bases compared. Indices of different lengths
+	// are only compared over their shared prefix.
+	Compared int
+}
+
+// IndexCollisions reports every pair of samples in the same lane that a
+// demultiplexer allowing the given number of mismatches in each index can not
+// tell apart. As with bcl2fastq and BCL Convert, a pair collides when both its
+// Index and its Index2 are within 2*mismatches of each other, so the distances
+// of the two indices are not added together. Samples on several lanes are checked in each of them, and samples with
+// a blank lane are checked in every lane. A lane that can not be parsed is
+// compared as it is written in the runsheet.
+func (r RunSheet) IndexCollisions(mismatches int) []IndexCollision {
+	lanes := []string{}
+	byLane := make(map[string][]Sample)
+	blank := []Sample{}
+	for _, s := range r.Samples {
+		ns, err := r.Lanes(s)
+		if err != nil && strings.TrimSpace(s.Lane) == "" {
+			// The lanes on the flow cell are not known, so compare
+			// against the lanes the other samples are on.
+			blank = append(blank, s)
+			continue
+		}
+		keys := []string{s.Lane}
+		if err == nil && len(ns) > 0 {
+			keys = keys[:0]
+			for _, n := range ns {
+				keys = append(keys, strconv.Itoa(n))
+			}
+		}
+		for _, key := range keys {
+			if _, ok := byLane[key]; !ok {
+				lanes = append(lanes, key)
+			}
+			byLane[key] = append(byLane[key], s)
+		}
+	}
+	for _, key := range lanes {
+		byLane[key] = append(byLane[key], blank...)
+	}
+	collisions := []IndexCollision{}
+	for _, lane := range lanes {
+		samples := byLane[lane]
+		for i := 0; i < len(samples); i++ {
+			for j := i + 1; j < len(samples); j++ {
+				a, b := samples[i], samples[j]
+				d1, n1 := prefixHamming(a.Index, b.Index)
+				d2, n2 := prefixHamming(a.Index2, b.Index2)
+				if d1 <= 2*mismatches && d2 <= 2*mismatches {
+					collisions = append(collisions, IndexCollision{
+						Lane:           lane,
+						A:              a,
+						B:              b,
+						IndexDistance:  d1,
+						Index2Distance: d2,
+						Compared:       n1 + n2,
+					})
+				}
+			}
+		}
+	}
+	return collisions
+}
+
+// prefixHamming returns the Hamming distance between a and b over their shared
+// prefix and the length of that prefix. The comparison ignores case.
+func prefixHamming(a, b string) (distance, n int) {
+	a, b = strings.ToUpper(a), strings.ToUpper(b)
+	n = len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			distance++
+		}
+	}
+	return distance, n
+}
+
+// ValidateIndexes checks each sample's Index and Index2 against the index read
+// cycles in the header. Indices must only contain ACGTN, must not be longer
+// than the cycle count, and must be present exactly when the index is read.
+func (r RunSheet) ValidateIndexes() []error {
+	errs := []error{}
+	for _, s := range r.Samples {
+		if err := validateIndex("Index", s.Index, r.Header.I7IndexReadCycles); err != nil {
+			errs = append(errs, fmt.Errorf("sample %s: %w", s.ID, err))
+		}
+		if err := validateIndex("Index2", s.Index2, r.Header.I5IndexReadCycles); err != nil {
+			errs = append(errs, fmt.Errorf("sample %s: %w", s.ID, err))
+		}
+	}
+	return errs
+}
+
+func validateIndex(name, index string, cycles int) error {
+	if cycles == 0 {
+		if index != "" {
+			return fmt.Errorf("%s %s is set but the index is not read", name, index)
+		}
+		return nil
+	}
+	if index == "" {
+		return fmt.Errorf("%s is missing but %d cycles are read", name, cycles)
+	}
+	if i := strings.IndexFunc(strings.ToUpper(index), func(c rune) bool { return !strings.ContainsRune("ACGTN", c) }); i >= 0 {
+		return fmt.Errorf("%s %s contains '%c'", name, index, index[i])
+	}
+	if len(index) > cycles {
+		return fmt.Errorf("%s %s is longer than the %d cycles read", name, index, cycles)
+	}
+	return nil
+}
+
+// OverrideCycles returns the BCL Convert OverrideCycles value for the sample,
+// such as "Y151;I8N2;I8N2;Y151" for 8 base indices read with 10 cycles.
+func (r RunSheet) OverrideCycles(s Sample) (string, error) {
+	h := r.Header
+	parts := []string{fmt.Sprintf("Y%d", h.Read1Cycles)}
+	for _, index := range []struct {
+		name   string
+		seq    string
+		cycles int
+	}{
+		{"Index", s.Index, h.I7IndexReadCycles},
+		{"Index2", s.Index2, h.I5IndexReadCycles},
+	} {
+		if index.cycles == 0 {
+			continue
+		}
+		n := len(index.seq)
+		switch {
+		case n > index.cycles:
+			return "", fmt.Errorf("sample %s: %s %s is longer than the %d cycles read", s.ID, index.name, index.seq, index.cycles)
+		case n == index.cycles:
+			parts = append(parts, fmt.Sprintf("I%d", n))
+		case n == 0:
+			parts = append(parts, fmt.Sprintf("N%d", index.cycles))
+		default:
+			parts = append(parts, fmt.Sprintf("I%dN%d", n, index.cycles-n))
+		}
+	}
+	if h.Read2Cycles > 0 {
+		parts = append(parts, fmt.Sprintf("Y%d", h.Read2Cycles))
+	}
+	return strings.Join(parts, ";"), nil
+}
@@ -0,0 +1,74 @@
+package runsheet
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// Layout describes where the data is in a runsheet workbook.
+type Layout struct {
+	SheetName string
+	// HeaderOffset is the number of rows between the "[Data]" marker and the
+	// sample column headers.
+	HeaderOffset int
+}
+
+// defaultLayout is the layout of the NovaSeq runsheet template, which the
+// other instruments' templates were copied from.
+var defaultLayout = Layout{SheetName: "SampleRunSheet", HeaderOffset: 3}
+
+// Instrument describes the runsheets for one kind of sequencer.
+type Instrument struct {
+	Name string
+	// Patterns are filepath.Match patterns matched against the base name of
+	// a runsheet file.
+	Patterns []string
+	Layout   Layout
+}
+
+// Matches reports whether the file name is a runsheet for the instrument.
+func (i Instrument) Matches(fn string) bool {
+	name := filepath.Base(fn)
+	for _, pattern := range i.Patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	instrumentsMu sync.RWMutex
+	// instruments are checked in order, so patterns that are a more specific
+	// form of another must come first.
+	instruments = []Instrument{
+		{
+			Name:     "NovaSeq X",
+			Patterns: []string{"NovaSeqX*.xlsx", "NovaSeq X*.xlsx", "NovaSeq_X*.xlsx", "NovaSeq-X*.xlsx"},
+			Layout:   defaultLayout,
+		},
+		{Name: "NovaSeq", Patterns: []string{"NovaSeq*.xlsx"}, Layout: defaultLayout},
+		{Name: "NextSeq", Patterns: []string{"NextSeq*.xlsx"}, Layout: defaultLayout},
+		{Name: "MiSeq", Patterns: []string{"MiSeq*.xlsx"}, Layout: defaultLayout},
+	}
+)
+
+// RegisterInstrument adds an instrument to the registry. It is checked before
+// the instruments already registered, so it can override their patterns.
+func RegisterInstrument(i Instrument) {
+	instrumentsMu.Lock()
+	defer instrumentsMu.Unlock()
+	instruments = append([]Instrument{i}, instruments...)
+}
+
+// InstrumentFor returns the instrument whose patterns match the file name.
+func InstrumentFor(fn string) (Instrument, bool) {
+	instrumentsMu.RLock()
+	defer instrumentsMu.RUnlock()
+	for _, i := range instruments {
+		if i.Matches(fn) {
+			return i, true
+		}
+	}
+	return Instrument{}, false
+}
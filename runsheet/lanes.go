@@ -0,0 +1,158 @@
+package runsheet
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// flowCellLanes is the number of lanes on each flow cell type.
+var flowCellLanes = map[string]int{
+	"SP":   2,
+	"S1":   2,
+	"S2":   2,
+	"S4":   4,
+	"P1":   1,
+	"P2":   1,
+	"P3":   1,
+	"1.5B": 2,
+	"10B":  8,
+	"25B":  8,
+}
+
+// LaneCount returns the number of lanes on the header's FlowCellType. Values
+// such as "S4", "NovaSeq S4" and "P3 (300 cycles)" are recognised.
+func (h Header) LaneCount() (int, error) {
+	t, ok := normaliseFlowCellType(h.FlowCellType)
+	if !ok {
+		return 0, fmt.Errorf("unknown flow cell type '%s'", h.FlowCellType)
+	}
+	return flowCellLanes[t], nil
+}
+
+// normaliseFlowCellType picks the known flow cell type out of a value such as
+// "NovaSeq S4".
+func normaliseFlowCellType(value string) (string, bool) {
+	tokens := strings.FieldsFunc(strings.ToUpper(value), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '.'
+	})
+	for _, t := range tokens {
+		if _, ok := flowCellLanes[t]; ok {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// ParseLanes parses a runsheet lane such as "1", "1+2", "1,3", "1-4" or "all"
+// into sorted lane numbers. laneCount is the number of lanes on the flow cell
+// and is needed for "all"; pass zero if it is not known. A blank lane returns
+// no lanes.
+func ParseLanes(lane string, laneCount int) ([]int, error) {
+	lane = strings.TrimSpace(lane)
+	if lane == "" {
+		return nil, nil
+	}
+	if strings.EqualFold(lane, "all") {
+		if laneCount == 0 {
+			return nil, fmt.Errorf("lane 'all' needs a known flow cell type")
+		}
+		lanes := make([]int, laneCount)
+		for i := range lanes {
+			lanes[i] = i + 1
+		}
+		return lanes, nil
+	}
+	seen := make(map[int]bool)
+	for _, part := range strings.FieldsFunc(lane, func(c rune) bool { return c == '+' || c == ',' }) {
+		bounds := strings.SplitN(part, "-", 2)
+		from, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid lane '%s'", lane)
+		}
+		to := from
+		if len(bounds) == 2 {
+			to, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+			if err != nil || to < from {
+				return nil, fmt.Errorf("invalid lane '%s'", lane)
+			}
+		}
+		for n := from; n <= to; n++ {
+			if n < 1 {
+				return nil, fmt.Errorf("invalid lane '%s'", lane)
+			}
+			seen[n] = true
+		}
+	}
+	lanes := make([]int, 0, len(seen))
+	for n := range seen {
+		lanes = append(lanes, n)
+	}
+	sort.Ints(lanes)
+	return lanes, nil
+}
+
+// Lanes returns the lane numbers of a sample. It is an error for a lane to be
+// beyond the lanes of the header's FlowCellType, when that is known. A sample
+// with a blank lane is on every lane when other samples have lanes, which
+// needs a known FlowCellType; otherwise it has no lanes.
+func (r RunSheet) Lanes(s Sample) ([]int, error) {
+	laneCount, _ := r.Header.LaneCount()
+	lane := s.Lane
+	if strings.TrimSpace(lane) == "" && r.hasLanes() {
+		if laneCount == 0 {
+			return nil, fmt.Errorf("sample %s: lane is blank but other samples have lanes and the flow cell type '%s' is not known", s.ID, r.Header.FlowCellType)
+		}
+		lane = "all"
+	}
+	lanes, err := ParseLanes(lane, laneCount)
+	if err != nil {
+		return nil, fmt.Errorf("sample %s: %w", s.ID, err)
+	}
+	for _, n := range lanes {
+		if laneCount > 0 && n > laneCount {
+			return nil, fmt.Errorf("sample %s: lane %d does not exist on a %s flow cell with %d lanes", s.ID, n, r.Header.FlowCellType, laneCount)
+		}
+	}
+	return lanes, nil
+}
+
+// ValidateLanes checks that every sample's lane parses and exists on the flow
+// cell given by Header.FlowCellType.
+func (r RunSheet) ValidateLanes() []error {
+	errs := []error{}
+	if _, err := r.Header.LaneCount(); err != nil && r.hasLanes() {
+		errs = append(errs, err)
+	}
+	for _, s := range r.Samples {
+		if _, err := r.Lanes(s); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// ExpandLanes returns the samples with one row per lane, each with Lane set to
+// a single lane number. Samples are only returned without a lane when no
+// sample has one.
+func (r RunSheet) ExpandLanes() ([]Sample, error) {
+	samples := []Sample{}
+	for _, s := range r.Samples {
+		lanes, err := r.Lanes(s)
+		if err != nil {
+			return nil, err
+		}
+		if len(lanes) == 0 {
+			samples = append(samples, s)
+			continue
+		}
+		for _, n := range lanes {
+			x := s
+			x.Lane = strconv.Itoa(n)
+			samples = append(samples, x)
+		}
+	}
+	return samples, nil
+}
@@ -0,0 +1,208 @@
+package runsheet
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const lookupFile = "lookup.csv"
+
+var lookupHeader = []string{"run_number", "filename", "instrument", "flowcell_id", "mtime"}
+
+// LookupEntry is a row of lookup.csv. Hand-maintained files only have the
+// run number and filename.
+type LookupEntry struct {
+	RunNumber  string
+	Filename   string
+	Instrument string
+	FlowCellID string
+	ModTime    time.Time
+}
+
+// LookupUpdate is the result of UpdateLookup.
+type LookupUpdate struct {
+	Entries []LookupEntry
+	// Added, Updated and Removed list the runsheet files whose entries
+	// changed.
+	Added   []string
+	Updated []string
+	Removed []string
+	// Warnings describe duplicate run numbers, entries for files that no
+	// longer exist and runsheets that could not be parsed.
+	Warnings []string
+}
+
+// readLookup reads the lookup.csv in runsheetdir and returns absolute
+// filenames. Relative filenames are resolved against the directory, or against
+// the working directory for hand-maintained files that were written that way.
+func readLookup(runsheetdir string) ([]LookupEntry, error) {
+	f, err := os.Open(filepath.Join(runsheetdir, lookupFile))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	entries := []LookupEntry{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 2 || record[0] == lookupHeader[0] {
+			continue
+		}
+		e := LookupEntry{RunNumber: record[0], Filename: record[1]}
+		if !filepath.IsAbs(e.Filename) {
+			fn := filepath.Join(runsheetdir, e.Filename)
+			if _, err := os.Stat(fn); err == nil {
+				e.Filename = fn
+			} else if _, err := os.Stat(e.Filename); err != nil {
+				e.Filename = fn
+			}
+		}
+		if e.Filename, err = filepath.Abs(e.Filename); err != nil {
+			return nil, err
+		}
+		if len(record) >= 5 {
+			e.Instrument = record[2]
+			e.FlowCellID = record[3]
+			e.ModTime, _ = time.Parse(time.RFC3339, record[4])
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// writeLookup replaces the lookup.csv in runsheetdir. Files in the directory
+// are written relative to it, so the lookup works from any working directory;
+// other files are written as absolute paths.
+func writeLookup(runsheetdir string, entries []LookupEntry) error {
+	dir, err := filepath.Abs(runsheetdir)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(runsheetdir, ".lookup-*.csv")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	cw := csv.NewWriter(tmp)
+	cw.Write(lookupHeader)
+	for _, e := range entries {
+		fn := e.Filename
+		if rel, err := filepath.Rel(dir, fn); err == nil && !strings.HasPrefix(rel, "..") {
+			fn = rel
+		}
+		cw.Write([]string{e.RunNumber, fn, e.Instrument, e.FlowCellID, e.ModTime.Format(time.RFC3339)})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(runsheetdir, lookupFile))
+}
+
+// UpdateLookup rebuilds the lookup.csv in runsheetdir. Runsheets whose
+// modification time matches their entry are not parsed again, and a runsheet
+// that can no longer be parsed keeps its previous entry with a warning.
+// Entries for files that no longer exist are dropped with a warning, and run
+// numbers that appear more than once are reported. Filenames in the result
+// are absolute.
+func UpdateLookup(runsheetdir string) (LookupUpdate, error) {
+	existing, err := readLookup(runsheetdir)
+	if err != nil && !os.IsNotExist(err) {
+		return LookupUpdate{}, fmt.Errorf("failed to read %s: %w", lookupFile, err)
+	}
+	byFile := make(map[string]LookupEntry)
+	for _, e := range existing {
+		byFile[e.Filename] = e
+	}
+
+	update := LookupUpdate{}
+	seen := make(map[string]bool)
+	for _, fn := range Find(runsheetdir, []string{}) {
+		if fn, err = filepath.Abs(fn); err != nil {
+			return LookupUpdate{}, err
+		}
+		seen[fn] = true
+		info, err := os.Stat(fn)
+		if err != nil {
+			return LookupUpdate{}, err
+		}
+		modTime := info.ModTime().Truncate(time.Second)
+		old, ok := byFile[fn]
+		if ok && old.ModTime.Equal(modTime) {
+			update.Entries = append(update.Entries, old)
+			continue
+		}
+		sheet, err := New(fn)
+		if err != nil {
+			if ok {
+				update.Entries = append(update.Entries, old)
+				update.Warnings = append(update.Warnings, fmt.Sprintf("unable to parse %s, keeping its previous entry: %v", fn, err))
+			} else {
+				update.Warnings = append(update.Warnings, fmt.Sprintf("unable to parse %s: %v", fn, err))
+			}
+			continue
+		}
+		update.Entries = append(update.Entries, LookupEntry{
+			RunNumber:  sheet.Header.RunNumber,
+			Filename:   fn,
+			Instrument: sheet.Header.InstrumentName,
+			FlowCellID: sheet.Header.FlowCellID,
+			ModTime:    modTime,
+		})
+		if ok {
+			update.Updated = append(update.Updated, fn)
+		} else {
+			update.Added = append(update.Added, fn)
+		}
+	}
+	// Keep hand-made entries for files that exist but are not named like a
+	// runsheet.
+	for _, e := range existing {
+		if seen[e.Filename] {
+			continue
+		}
+		if _, err := os.Stat(e.Filename); err != nil {
+			update.Removed = append(update.Removed, e.Filename)
+			update.Warnings = append(update.Warnings, fmt.Sprintf("run %s: %s no longer exists", e.RunNumber, e.Filename))
+			continue
+		}
+		update.Entries = append(update.Entries, e)
+	}
+
+	sort.Slice(update.Entries, func(i, j int) bool {
+		a, b := update.Entries[i], update.Entries[j]
+		if a.RunNumber != b.RunNumber {
+			return a.RunNumber < b.RunNumber
+		}
+		return a.Filename < b.Filename
+	})
+	for i := 1; i < len(update.Entries); i++ {
+		a, b := update.Entries[i-1], update.Entries[i]
+		if a.RunNumber == b.RunNumber {
+			update.Warnings = append(update.Warnings, fmt.Sprintf("run %s: found in %s and %s", a.RunNumber, a.Filename, b.Filename))
+		}
+	}
+
+	if err := writeLookup(runsheetdir, update.Entries); err != nil {
+		return LookupUpdate{}, fmt.Errorf("failed to write %s: %w", lookupFile, err)
+	}
+	return update, nil
+}
@@ -0,0 +1,143 @@
+package runsheet
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jje42/atgclogs/weslog"
+)
+
+// Pipeline is an nf-core pipeline that takes a samplesheet.
+type Pipeline int
+
+const (
+	Sarek Pipeline = iota
+	RNAseq
+)
+
+// NfcoreOptions controls WriteNfcoreSampleSheet.
+type NfcoreOptions struct {
+	// WESLog, keyed by UIN as returned by weslog.New, supplies the
+	// SubjectID, sex and tumour/normal status for Sarek. It may be nil. The
+	// status comes from SampleType and TissueSourceType, which must each be
+	// blank or one of the values in sarekStatus.
+	WESLog map[string]weslog.Sample
+	// Strandedness is written for RNAseq and defaults to "auto".
+	Strandedness string
+}
+
+// WriteNfcoreSampleSheet writes an nf-core samplesheet for the pipeline from
+// the FASTQs resolved by ResolveFastqs. Each UIN is one nf-core sample with a
+// row per lane, in the order the UINs first appear in the runsheet. Sets
+// without the FASTQs the run should have produced are left out; they are
+// listed in the Missing field of the report.
+func (r RunSheet) WriteNfcoreSampleSheet(w io.Writer, pipeline Pipeline, fastqs FastqReport, opts NfcoreOptions) error {
+	uins := []string{}
+	byUIN := make(map[string][]FastqSet)
+	for _, s := range r.Samples {
+		if _, ok := byUIN[s.UIN]; !ok {
+			uins = append(uins, s.UIN)
+			byUIN[s.UIN] = []FastqSet{}
+		}
+	}
+	for _, set := range fastqs.Sets {
+		if set.R1 == "" || (r.Header.Read2Cycles > 0 && set.R2 == "") {
+			continue
+		}
+		if _, ok := byUIN[set.Sample.UIN]; ok {
+			byUIN[set.Sample.UIN] = append(byUIN[set.Sample.UIN], set)
+		}
+	}
+
+	var records [][]string
+	switch pipeline {
+	case Sarek:
+		records = append(records, []string{"patient", "sex", "status", "sample", "lane", "fastq_1", "fastq_2"})
+		for _, uin := range uins {
+			lanes := make(map[string]bool)
+			for _, set := range byUIN[uin] {
+				patient, sex, status, err := sarekSubject(set.Sample, opts.WESLog)
+				if err != nil {
+					return err
+				}
+				// A UIN may be sequenced under several sample IDs in
+				// one lane, but Sarek needs a distinct lane name.
+				lane := fmt.Sprintf("L%03d", set.Lane)
+				if lanes[lane] {
+					lane = fmt.Sprintf("%s_L%03d", set.Sample.ID, set.Lane)
+				}
+				lanes[lane] = true
+				records = append(records, []string{patient, sex, status, uin, lane, set.R1, set.R2})
+			}
+		}
+	case RNAseq:
+		strandedness := opts.Strandedness
+		if strandedness == "" {
+			strandedness = "auto"
+		}
+		records = append(records, []string{"sample", "fastq_1", "fastq_2", "strandedness"})
+		for _, uin := range uins {
+			for _, set := range byUIN[uin] {
+				records = append(records, []string{uin, set.R1, set.R2, strandedness})
+			}
+		}
+	default:
+		return fmt.Errorf("unknown pipeline: %d", pipeline)
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(records); err != nil {
+		return fmt.Errorf("failed to write samplesheet: %w", err)
+	}
+	return nil
+}
+
+// sarekStatus maps the lower case SampleType and TissueSourceType values of the
+// WES log onto the Sarek status column.
+var sarekStatus = map[string]string{
+	"tumour":   "1",
+	"tumor":    "1",
+	"normal":   "0",
+	"germline": "0",
+	"blood":    "0",
+	"saliva":   "0",
+}
+
+// sarekSubject returns the patient, sex and status columns for a sample,
+// preferring the WES log over the runsheet. A sample is a tumour if either
+// WES log column says so. Values not in sarekStatus are an error, so that a
+// normal is never paired as a tumour by mistake.
+func sarekSubject(s Sample, wesLog map[string]weslog.Sample) (patient, sex, status string, err error) {
+	patient = s.SubjectID
+	sex = "NA"
+	status = "0"
+	if w, ok := wesLog[s.UIN]; ok {
+		if w.SubjectID != "" {
+			patient = w.SubjectID
+		}
+		switch w.Gender {
+		case "MALE":
+			sex = "XY"
+		case "FEMALE":
+			sex = "XX"
+		}
+		for _, v := range []string{w.SampleType, w.TissueSourceType} {
+			key := strings.ToLower(strings.TrimSpace(v))
+			if key == "" || key == "na" {
+				continue
+			}
+			st, ok := sarekStatus[key]
+			if !ok {
+				return "", "", "", fmt.Errorf("sample %s: unable to tell whether '%s' is tumour or normal", s.UIN, v)
+			}
+			if st == "1" {
+				status = st
+			}
+		}
+	}
+	if patient == "" {
+		patient = s.UIN
+	}
+	return patient, sex, status, nil
+}
@@ -0,0 +1,214 @@
+package runsheet
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Orientation is the strand the i5 index is read on.
+type Orientation int
+
+const (
+	// OrientationForward is the forward strand workflow: the instrument
+	// reads Index2 as it is written in the runsheet.
+	OrientationForward Orientation = iota
+	// OrientationReverseComplement is the reverse complement workflow: the
+	// instrument reads the reverse complement of Index2.
+	OrientationReverseComplement
+)
+
+func (o Orientation) String() string {
+	if o == OrientationReverseComplement {
+		return "reverse complement"
+	}
+	return "forward"
+}
+
+// i5Rules records how each instrument and reagent version reads the i5 index.
+// An empty Version matches any version. Index2 in the runsheet is always the
+// forward strand sequence.
+var i5Rules = []struct {
+	Platform    string
+	Version     string
+	Orientation Orientation
+}{
+	{"NovaSeq", "1.0", OrientationForward},
+	{"NovaSeq", "1.5", OrientationReverseComplement},
+	{"NovaSeq X", "", OrientationReverseComplement},
+	{"NextSeq 2000", "", OrientationReverseComplement},
+	{"NextSeq 500", "", OrientationReverseComplement},
+	{"NextSeq", "", OrientationReverseComplement},
+	{"MiSeq", "", OrientationForward},
+}
+
+// Demultiplexer is the software a sample sheet is written for.
+type Demultiplexer int
+
+const (
+	BCLConvert Demultiplexer = iota
+	Bcl2fastq
+)
+
+// correctsI5 lists the platforms that mark the i5 read IsReverseComplement in
+// RunInfo.xml. BCL Convert reverse complements the i5 itself on these, so its
+// sample sheet takes the forward strand sequence.
+var correctsI5 = map[string]bool{
+	"NovaSeq X":    true,
+	"NextSeq 2000": true,
+}
+
+// instrumentPrefixes maps the prefix of an Illumina instrument serial number
+// onto its platform. Longer prefixes come first.
+var instrumentPrefixes = []struct {
+	Prefix   string
+	Platform string
+}{
+	{"LH", "NovaSeq X"},
+	{"VH", "NextSeq 2000"},
+	{"VL", "NextSeq 2000"},
+	{"NB", "NextSeq 500"},
+	{"NS", "NextSeq 500"},
+	{"A", "NovaSeq"},
+	{"M", "MiSeq"},
+}
+
+// Platform returns the sequencing platform. It is taken from the serial number
+// in InstrumentName where possible, otherwise from the instrument the runsheet
+// file name matched.
+func (r RunSheet) Platform() string {
+	if p := platformFor(r.Header.InstrumentName); p != "" {
+		return p
+	}
+	return r.Instrument
+}
+
+// platformFor returns the platform named by an instrument serial number, or
+// an empty string if it is not recognised.
+func platformFor(instrument string) string {
+	name := strings.ToUpper(strings.TrimSpace(instrument))
+	for _, p := range instrumentPrefixes {
+		rest := strings.TrimPrefix(name, p.Prefix)
+		if rest != name && rest != "" && rest[0] >= '0' && rest[0] <= '9' {
+			return p.Platform
+		}
+	}
+	return ""
+}
+
+// I5Orientation returns how the instrument reads the i5 index. A Workflow in
+// the header that names the forward or reverse complement workflow takes
+// precedence over the rules for the platform and reagent version.
+func (r RunSheet) I5Orientation() (Orientation, error) {
+	workflow := strings.ToLower(r.Header.Workflow)
+	switch {
+	case strings.Contains(workflow, "reverse complement"):
+		return OrientationReverseComplement, nil
+	case strings.Contains(workflow, "forward"):
+		return OrientationForward, nil
+	}
+	platform := r.Platform()
+	version := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(r.Header.Version)), "v")
+	for _, rule := range i5Rules {
+		if rule.Platform == platform && (rule.Version == "" || rule.Version == version) {
+			return rule.Orientation, nil
+		}
+	}
+	return OrientationForward, fmt.Errorf("unknown i5 orientation for platform '%s' version '%s'", platform, r.Header.Version)
+}
+
+// SampleSheetOrientation returns the orientation the demultiplexer expects
+// Index2 in. This is the orientation the instrument reads the i5 in, except
+// for BCL Convert on platforms in correctsI5, which take the forward strand.
+func (r RunSheet) SampleSheetOrientation(d Demultiplexer) (Orientation, error) {
+	o, err := r.I5Orientation()
+	if err != nil {
+		return o, err
+	}
+	if d == BCLConvert && correctsI5[r.Platform()] {
+		return OrientationForward, nil
+	}
+	return o, nil
+}
+
+// DemuxIndex2 returns the Index2 of the sample as the demultiplexer expects it
+// in its sample sheet.
+func (r RunSheet) DemuxIndex2(s Sample, d Demultiplexer) (string, error) {
+	o, err := r.SampleSheetOrientation(d)
+	if err != nil {
+		return "", err
+	}
+	if o == OrientationReverseComplement {
+		return ReverseComplement(s.Index2), nil
+	}
+	return s.Index2, nil
+}
+
+// ReverseComplement returns the reverse complement of a DNA sequence. Bases
+// other than ACGT are left as they are.
+func ReverseComplement(seq string) string {
+	b := []byte(strings.ToUpper(seq))
+	for i, j := 0, len(b)-1; i <= j; i, j = i+1, j-1 {
+		b[i], b[j] = complement(b[j]), complement(b[i])
+	}
+	return string(b)
+}
+
+func complement(c byte) byte {
+	switch c {
+	case 'A':
+		return 'T'
+	case 'C':
+		return 'G'
+	case 'G':
+		return 'C'
+	case 'T':
+		return 'A'
+	}
+	return c
+}
+
+// Index2Warning is a sample whose Index2 looks like it has already been
+// reverse complemented.
+type Index2Warning struct {
+	Sample  Sample
+	Message string
+}
+
+func (w Index2Warning) String() string {
+	return fmt.Sprintf("sample %s: %s", w.Sample.ID, w.Message)
+}
+
+// CheckIndex2Orientation looks for samples whose Index2 appears to have been
+// reverse complemented already. known maps I5IndexID onto the forward strand
+// sequence from the index kit and may be nil. Without it, samples sharing an
+// I5IndexID are compared with each other.
+func (r RunSheet) CheckIndex2Orientation(known map[string]string) []Index2Warning {
+	warnings := []Index2Warning{}
+	first := make(map[string]Sample)
+	for _, s := range r.Samples {
+		if s.Index2 == "" {
+			continue
+		}
+		index2 := strings.ToUpper(s.Index2)
+		if seq, ok := known[s.I5IndexID]; ok {
+			seq = strings.ToUpper(seq)
+			if index2 != seq && index2 == ReverseComplement(seq) {
+				warnings = append(warnings, Index2Warning{s, fmt.Sprintf("Index2 %s is the reverse complement of %s", s.Index2, s.I5IndexID)})
+			}
+			continue
+		}
+		if s.I5IndexID == "" {
+			continue
+		}
+		other, ok := first[s.I5IndexID]
+		if !ok {
+			first[s.I5IndexID] = s
+			continue
+		}
+		seq := strings.ToUpper(other.Index2)
+		if index2 != seq && index2 == ReverseComplement(seq) {
+			warnings = append(warnings, Index2Warning{s, fmt.Sprintf("Index2 %s is the reverse complement of the Index2 of sample %s, which has the same I5IndexID %s", s.Index2, other.ID, s.I5IndexID)})
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,232 @@
+package runsheet
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RunInfoRead is a read from RunInfo.xml.
+type RunInfoRead struct {
+	Number    int
+	NumCycles int
+	IsIndexed bool
+}
+
+// RunInfo is the part of a run folder's RunInfo.xml that is compared with the
+// runsheet.
+type RunInfo struct {
+	ID         string
+	Number     int
+	Flowcell   string
+	Instrument string
+	Reads      []RunInfoRead
+	LaneCount  int
+}
+
+// ReadRunInfo parses a RunInfo.xml file.
+func ReadRunInfo(fn string) (RunInfo, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return RunInfo{}, err
+	}
+	defer f.Close()
+	var doc struct {
+		Run struct {
+			ID         string `xml:"Id,attr"`
+			Number     int    `xml:"Number,attr"`
+			Flowcell   string `xml:"Flowcell"`
+			Instrument string `xml:"Instrument"`
+			Reads      []struct {
+				Number        int    `xml:"Number,attr"`
+				NumCycles     int    `xml:"NumCycles,attr"`
+				IsIndexedRead string `xml:"IsIndexedRead,attr"`
+			} `xml:"Reads>Read"`
+			FlowcellLayout struct {
+				LaneCount int `xml:"LaneCount,attr"`
+			} `xml:"FlowcellLayout"`
+		} `xml:"Run"`
+	}
+	if err := xml.NewDecoder(f).Decode(&doc); err != nil {
+		return RunInfo{}, fmt.Errorf("failed to parse %s: %w", fn, err)
+	}
+	info := RunInfo{
+		ID:         doc.Run.ID,
+		Number:     doc.Run.Number,
+		Flowcell:   doc.Run.Flowcell,
+		Instrument: doc.Run.Instrument,
+		LaneCount:  doc.Run.FlowcellLayout.LaneCount,
+	}
+	for _, r := range doc.Run.Reads {
+		info.Reads = append(info.Reads, RunInfoRead{
+			Number:    r.Number,
+			NumCycles: r.NumCycles,
+			IsIndexed: r.IsIndexedRead == "Y",
+		})
+	}
+	return info, nil
+}
+
+// cycles returns the cycles of the sequencing reads and the index reads in
+// the order they are read.
+func (info RunInfo) cycles() (reads, indexes []int) {
+	for _, r := range info.Reads {
+		if r.IsIndexed {
+			indexes = append(indexes, r.NumCycles)
+		} else {
+			reads = append(reads, r.NumCycles)
+		}
+	}
+	return reads, indexes
+}
+
+// RunParameters holds the text of each element in RunParameters.xml keyed by
+// element name. The layout of the file differs between instruments, so only
+// the first element with a given name is kept.
+type RunParameters map[string]string
+
+// Get returns the value of the first of names that is present.
+func (p RunParameters) Get(names ...string) string {
+	for _, name := range names {
+		if v, ok := p[name]; ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// ReadRunParameters parses a RunParameters.xml file.
+func ReadRunParameters(fn string) (RunParameters, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p := make(RunParameters)
+	d := xml.NewDecoder(f)
+	var name string
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", fn, err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			name = t.Name.Local
+		case xml.CharData:
+			v := strings.TrimSpace(string(t))
+			if _, ok := p[name]; !ok && name != "" && v != "" {
+				p[name] = v
+			}
+		case xml.EndElement:
+			name = ""
+		}
+	}
+	return p, nil
+}
+
+// Mismatch is a field that differs between the runsheet and the run folder.
+type Mismatch struct {
+	Field     string
+	RunSheet  string
+	RunFolder string
+}
+
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s: runsheet has '%s', run folder has '%s'", m.Field, m.RunSheet, m.RunFolder)
+}
+
+// CompareRunFolder compares the header with the RunInfo.xml and, if present,
+// RunParameters.xml in a sequencer run folder. Fields that are missing from
+// the run folder are not compared.
+func (r RunSheet) CompareRunFolder(dir string) ([]Mismatch, error) {
+	info, err := ReadRunInfo(filepath.Join(dir, "RunInfo.xml"))
+	if err != nil {
+		return nil, err
+	}
+	params := RunParameters{}
+	for _, name := range []string{"RunParameters.xml", "runParameters.xml"} {
+		fn := filepath.Join(dir, name)
+		if _, err := os.Stat(fn); err != nil {
+			continue
+		}
+		params, err = ReadRunParameters(fn)
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	h := r.Header
+	mismatches := []Mismatch{}
+	compare := func(field, runsheet, runfolder string) {
+		if runfolder != "" && !strings.EqualFold(strings.TrimSpace(runsheet), strings.TrimSpace(runfolder)) {
+			mismatches = append(mismatches, Mismatch{field, runsheet, runfolder})
+		}
+	}
+
+	compare("flowcell ID", h.FlowCellID, info.Flowcell)
+	compare("instrument name", h.InstrumentName, info.Instrument)
+	if info.Number > 0 {
+		if n, err := strconv.Atoi(h.RunNumber); err != nil || n != info.Number {
+			mismatches = append(mismatches, Mismatch{"run number", h.RunNumber, strconv.Itoa(info.Number)})
+		}
+	}
+
+	side := params.Get("Side", "FlowCellSide")
+	if side == "" {
+		// The run ID ends in the side followed by the flowcell ID.
+		parts := strings.Split(info.ID, "_")
+		last := parts[len(parts)-1]
+		if info.Flowcell != "" && len(last) == len(info.Flowcell)+1 && strings.HasSuffix(last, info.Flowcell) {
+			side = last[:1]
+		}
+	}
+	compare("flowcell side", h.FlowCellPosition, side)
+
+	reads, indexes := info.cycles()
+	for _, c := range []struct {
+		field  string
+		cycles int
+		values []int
+		idx    int
+	}{
+		{"read 1 cycles", h.Read1Cycles, reads, 0},
+		{"read 2 cycles", h.Read2Cycles, reads, 1},
+		{"i7 index read cycles", h.I7IndexReadCycles, indexes, 0},
+		{"i5 index read cycles", h.I5IndexReadCycles, indexes, 1},
+	} {
+		if len(info.Reads) == 0 {
+			break
+		}
+		actual := 0
+		if c.idx < len(c.values) {
+			actual = c.values[c.idx]
+		}
+		if actual != c.cycles {
+			mismatches = append(mismatches, Mismatch{c.field, strconv.Itoa(c.cycles), strconv.Itoa(actual)})
+		}
+	}
+
+	if mode := params.Get("FlowCellMode", "FlowCellType"); mode != "" {
+		want, ok1 := normaliseFlowCellType(h.FlowCellType)
+		got, ok2 := normaliseFlowCellType(mode)
+		if !ok1 || !ok2 {
+			compare("flowcell type", h.FlowCellType, mode)
+		} else if want != got {
+			mismatches = append(mismatches, Mismatch{"flowcell type", h.FlowCellType, mode})
+		}
+	} else if info.LaneCount > 0 {
+		if n, err := h.LaneCount(); err == nil && n != info.LaneCount {
+			mismatches = append(mismatches, Mismatch{"lane count", strconv.Itoa(n), strconv.Itoa(info.LaneCount)})
+		}
+	}
+	return mismatches, nil
+}
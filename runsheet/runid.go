@@ -0,0 +1,188 @@
+package runsheet
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RunID identifies a sequencing run by the name of its run folder,
+// DATE_INSTRUMENT_RUNNUMBER_[SIDE]FLOWCELL. How each part is written depends
+// on the platform, see runFolderFormats.
+type RunID struct {
+	Date time.Time
+	// FullYear is set when the date has a four digit year.
+	FullYear   bool
+	Instrument string
+	RunNumber  int
+	// Side is empty on platforms that do not write one.
+	Side       string
+	FlowCellID string
+}
+
+// runFolderFormat describes how a platform names its run folders.
+type runFolderFormat struct {
+	// FullYear writes the date as YYYYMMDD rather than YYMMDD.
+	FullYear bool
+	// Side writes the flow cell side, A or B, before the flow cell ID.
+	Side bool
+	// PadRunNumber writes the run number with four digits.
+	PadRunNumber bool
+}
+
+// runFolderFormats maps a platform, as returned by platformFor, onto the way
+// it names run folders. Folders from other instruments are read with
+// defaultRunFolderFormat.
+var runFolderFormats = map[string]runFolderFormat{
+	"NovaSeq":      {FullYear: false, Side: true, PadRunNumber: true},
+	"NovaSeq X":    {FullYear: true, Side: true, PadRunNumber: true},
+	"NextSeq 500":  {FullYear: false, Side: true, PadRunNumber: true},
+	"NextSeq 2000": {FullYear: true, Side: false, PadRunNumber: false},
+	"MiSeq":        {FullYear: false, Side: false, PadRunNumber: true},
+}
+
+var defaultRunFolderFormat = runFolderFormat{FullYear: false, Side: true, PadRunNumber: true}
+
+// runFolderFormatFor returns the run folder format of an instrument and
+// whether its platform is known.
+func runFolderFormatFor(instrument string) (runFolderFormat, bool) {
+	f, ok := runFolderFormats[platformFor(instrument)]
+	if !ok {
+		return defaultRunFolderFormat, false
+	}
+	return f, true
+}
+
+var runIDPattern = regexp.MustCompile(`^(\d{6}|\d{8})_([A-Za-z0-9]+)_(\d+)_([A-Za-z0-9-]+)$`)
+
+// ParseRunID parses a run folder name. A path is accepted and only its base
+// name is parsed. Whether the flow cell ID is preceded by a side is decided by
+// the platform of the instrument serial number. For instruments that are not
+// recognised, a leading A or B is taken as the side unless the flow cell ID
+// contains a '-', as MiSeq and iSeq flow cell IDs do.
+func ParseRunID(name string) (RunID, error) {
+	base := filepath.Base(strings.TrimRight(name, "/"))
+	m := runIDPattern.FindStringSubmatch(base)
+	if m == nil {
+		return RunID{}, fmt.Errorf("invalid run folder name '%s': expected DATE_INSTRUMENT_RUNNUMBER_[SIDE]FLOWCELL", base)
+	}
+	id := RunID{Instrument: m[2], FlowCellID: m[4], FullYear: len(m[1]) == 8}
+	var err error
+	id.Date, err = time.Parse(id.dateLayout(), m[1])
+	if err != nil {
+		return RunID{}, fmt.Errorf("invalid date in run folder name '%s': %w", base, err)
+	}
+	id.RunNumber, err = strconv.Atoi(m[3])
+	if err != nil {
+		return RunID{}, fmt.Errorf("invalid run number in run folder name '%s': %w", base, err)
+	}
+	format, known := runFolderFormatFor(id.Instrument)
+	fc := id.FlowCellID
+	hasSide := len(fc) > 1 && (fc[0] == 'A' || fc[0] == 'B')
+	if known {
+		if format.Side && !hasSide {
+			return RunID{}, fmt.Errorf("invalid run folder name '%s': expected the flow cell side before the flow cell ID", base)
+		}
+		hasSide = format.Side
+	} else {
+		hasSide = hasSide && !strings.Contains(fc, "-")
+	}
+	if hasSide {
+		id.Side, id.FlowCellID = fc[:1], fc[1:]
+	}
+	return id, nil
+}
+
+func (id RunID) dateLayout() string {
+	if id.FullYear {
+		return "20060102"
+	}
+	return "060102"
+}
+
+// String returns the run folder name.
+func (id RunID) String() string {
+	format, _ := runFolderFormatFor(id.Instrument)
+	number := strconv.Itoa(id.RunNumber)
+	if format.PadRunNumber {
+		number = fmt.Sprintf("%04d", id.RunNumber)
+	}
+	return fmt.Sprintf("%s_%s_%s_%s%s", id.Date.Format(id.dateLayout()), id.Instrument, number, id.Side, id.FlowCellID)
+}
+
+// RunID builds the run identifier from the header. The flow cell position is
+// left out on platforms that do not write a side in the run folder name.
+func (r RunSheet) RunID() (RunID, error) {
+	h := r.Header
+	if h.SequencingStartDate.IsZero() {
+		return RunID{}, fmt.Errorf("sequencing start date is not set")
+	}
+	n, err := strconv.Atoi(h.RunNumber)
+	if err != nil {
+		return RunID{}, fmt.Errorf("invalid run number '%s'", h.RunNumber)
+	}
+	if h.InstrumentName == "" || h.FlowCellID == "" {
+		return RunID{}, fmt.Errorf("instrument name and flow cell ID must be set")
+	}
+	format, _ := runFolderFormatFor(h.InstrumentName)
+	id := RunID{
+		Date:       h.SequencingStartDate.Time,
+		FullYear:   format.FullYear,
+		Instrument: h.InstrumentName,
+		RunNumber:  n,
+		FlowCellID: h.FlowCellID,
+	}
+	if format.Side {
+		if h.FlowCellPosition == "" {
+			return RunID{}, fmt.Errorf("flow cell position must be set")
+		}
+		id.Side = h.FlowCellPosition
+	}
+	return id, nil
+}
+
+// ExpectedRunFolder returns the run folder name the header describes.
+func (r RunSheet) ExpectedRunFolder() (string, error) {
+	id, err := r.RunID()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// CheckRunFolderName reports the header fields that disagree with an actual
+// run folder name, including RunName when it is set. The flowcell side is
+// only compared when the folder name has one.
+func (r RunSheet) CheckRunFolderName(name string) ([]Mismatch, error) {
+	id, err := ParseRunID(name)
+	if err != nil {
+		return nil, err
+	}
+	h := r.Header
+	mismatches := []Mismatch{}
+	add := func(field, runsheet, runfolder string) {
+		if !strings.EqualFold(runsheet, runfolder) {
+			mismatches = append(mismatches, Mismatch{field, runsheet, runfolder})
+		}
+	}
+	date := ""
+	if !h.SequencingStartDate.IsZero() {
+		date = h.SequencingStartDate.Format(id.dateLayout())
+	}
+	add("sequencing start date", date, id.Date.Format(id.dateLayout()))
+	add("instrument name", h.InstrumentName, id.Instrument)
+	if n, err := strconv.Atoi(h.RunNumber); err != nil || n != id.RunNumber {
+		mismatches = append(mismatches, Mismatch{"run number", h.RunNumber, strconv.Itoa(id.RunNumber)})
+	}
+	if id.Side != "" {
+		add("flowcell side", h.FlowCellPosition, id.Side)
+	}
+	add("flowcell ID", h.FlowCellID, id.FlowCellID)
+	if h.RunName != "" {
+		add("run name", h.RunName, filepath.Base(strings.TrimRight(name, "/")))
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,111 @@
+package runsheet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jje42/atgclogs/weslog"
+)
+
+func TestParseRunID(t *testing.T) {
+	tests := []struct {
+		name string
+		want RunID
+	}{
+		{
+			"240315_A00123_0042_AHABCDEFXY",
+			RunID{Date: date(2024, 3, 15), Instrument: "A00123", RunNumber: 42, Side: "A", FlowCellID: "HABCDEFXY"},
+		},
+		{
+			"240315_A00123_0043_BHABCDEFXZ",
+			RunID{Date: date(2024, 3, 15), Instrument: "A00123", RunNumber: 43, Side: "B", FlowCellID: "HABCDEFXZ"},
+		},
+		{
+			"20230815_LH00123_0045_A22ABCDLT3",
+			RunID{Date: date(2023, 8, 15), FullYear: true, Instrument: "LH00123", RunNumber: 45, Side: "A", FlowCellID: "22ABCDLT3"},
+		},
+		{
+			"160805_NB501234_0010_AHFJ2LBGXY",
+			RunID{Date: date(2016, 8, 5), Instrument: "NB501234", RunNumber: 10, Side: "A", FlowCellID: "HFJ2LBGXY"},
+		},
+		{
+			"20230101_VH00123_12_AAAJ7FLHV",
+			RunID{Date: date(2023, 1, 1), FullYear: true, Instrument: "VH00123", RunNumber: 12, FlowCellID: "AAAJ7FLHV"},
+		},
+		{
+			"210102_M01234_0045_000000000-ABCDE",
+			RunID{Date: date(2021, 1, 2), Instrument: "M01234", RunNumber: 45, FlowCellID: "000000000-ABCDE"},
+		},
+	}
+	for _, tt := range tests {
+		got, err := ParseRunID("/data/runs/" + tt.name + "/")
+		if err != nil {
+			t.Errorf("ParseRunID(%q) returned error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRunID(%q) = %+v, want %+v", tt.name, got, tt.want)
+		}
+		if s := got.String(); s != tt.name {
+			t.Errorf("ParseRunID(%q).String() = %q", tt.name, s)
+		}
+	}
+}
+
+func TestParseRunIDInvalid(t *testing.T) {
+	for _, name := range []string{
+		"",
+		"240315_A00123_0042",
+		"2403_A00123_0042_AHABCDEFXY",
+		"240315_A00123_0042_HABCDEFXY",
+		"241315_A00123_0042_AHABCDEFXY",
+	} {
+		if _, err := ParseRunID(name); err == nil {
+			t.Errorf("ParseRunID(%q) did not return an error", name)
+		}
+	}
+}
+
+func TestExpectedRunFolder(t *testing.T) {
+	tests := []struct {
+		header Header
+		want   string
+	}{
+		{
+			Header{InstrumentName: "A00123", RunNumber: "0042", FlowCellPosition: "A", FlowCellID: "HABCDEFXY"},
+			"240315_A00123_0042_AHABCDEFXY",
+		},
+		{
+			Header{InstrumentName: "LH00123", RunNumber: "45", FlowCellPosition: "B", FlowCellID: "22ABCDLT3"},
+			"20240315_LH00123_0045_B22ABCDLT3",
+		},
+		{
+			Header{InstrumentName: "VH00123", RunNumber: "12", FlowCellPosition: "A", FlowCellID: "AAAJ7FLHV"},
+			"20240315_VH00123_12_AAAJ7FLHV",
+		},
+		{
+			Header{InstrumentName: "M01234", RunNumber: "45", FlowCellPosition: "A", FlowCellID: "000000000-ABCDE"},
+			"240315_M01234_0045_000000000-ABCDE",
+		},
+	}
+	for _, tt := range tests {
+		tt.header.SequencingStartDate = weslog.Date{Time: date(2024, 3, 15)}
+		r := RunSheet{Header: tt.header}
+		got, err := r.ExpectedRunFolder()
+		if err != nil {
+			t.Errorf("ExpectedRunFolder() for %s returned error: %v", tt.want, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ExpectedRunFolder() = %q, want %q", got, tt.want)
+		}
+		mismatches, err := r.CheckRunFolderName(tt.want)
+		if err != nil || len(mismatches) != 0 {
+			t.Errorf("CheckRunFolderName(%q) = %v, %v, want no mismatches", tt.want, mismatches, err)
+		}
+	}
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
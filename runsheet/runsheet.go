@@ -4,42 +4,85 @@ import (
 	"fmt"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jje42/atgclogs/weslog"
 	"github.com/xuri/excelize/v2"
 )
 
-const sheetName = "SampleRunSheet"
-
 type RunSheet struct {
-	Filename  string
-	f         *excelize.File
-	Header    Header
-	headerMap map[string]int
-	dataIdx   int
-	sheetName string
-	Samples   []Sample
+	Filename   string
+	Instrument string
+	f          *excelize.File
+	Header     Header
+	headerMap  map[string]int
+	dataIdx    int
+	headerRow  int
+	sheetName  string
+	Samples    []Sample
 }
 
 type Header struct {
-	SequencingStartDate string `csv:"sequencing_start_date"`
-	InstrumentName      string `csv:"instrument_name"`
-	RunNumber           string `csv:"run_number"`
-	FlowCellPosition    string `csv:"flowcell_position"`
-	FlowCellID          string `csv:"flowcell_id"`
-	RunName             string `csv:"run_name"`
-	FlowCellType        string `csv:"flowcell_type"`
-	Version             string `csv:"version"`
-	RunType             string `csv:"run_type"`
-	Workflow            string `csv:"workflow"`
-	Indexing            string `csv:"indexing"`
-	Read1Cycles         int    `csv:"read1_cycles"`
-	Read2Cycles         int    `csv:"read2_cycles"`
-	I7IndexReadCycles   int    `csv:"i7_index_read_cycles"`
-	I5IndexReadCycles   int    `csv:"i5_index_read_cycles"`
+	SequencingStartDate weslog.Date `csv:"sequencing_start_date"`
+	InstrumentName      string      `csv:"instrument_name"`
+	RunNumber           string      `csv:"run_number"`
+	FlowCellPosition    string      `csv:"flowcell_position"`
+	FlowCellID          string      `csv:"flowcell_id"`
+	RunName             string      `csv:"run_name"`
+	FlowCellType        string      `csv:"flowcell_type"`
+	Version             string      `csv:"version"`
+	RunType             string      `csv:"run_type"`
+	Workflow            string      `csv:"workflow"`
+	Indexing            string      `csv:"indexing"`
+	Read1Cycles         int         `csv:"read1_cycles"`
+	Read2Cycles         int         `csv:"read2_cycles"`
+	I7IndexReadCycles   int         `csv:"i7_index_read_cycles"`
+	I5IndexReadCycles   int         `csv:"i5_index_read_cycles"`
+}
+
+// SequencingStartDateString returns the sequencing start date in the
+// DD/MM/YYYY form the runsheet header used to store, or an empty string if it
+// is not set.
+func (h Header) SequencingStartDateString() string {
+	if h.SequencingStartDate.IsZero() {
+		return ""
+	}
+	return h.SequencingStartDate.Format("02/01/2006")
+}
+
+// runsheetDateLayouts are the text forms of the sequencing start date that
+// only appear in runsheets. Dashed dates with a two digit year are month
+// first, as runsheets have always been read.
+var runsheetDateLayouts = []string{
+	"01-02-06",
+	"2/1/2006",
+	"2006-01-02",
+}
+
+// parseDate parses a date cell, which may be an Excel date serial number or
+// text in one of runsheetDateLayouts or weslog.DateLayouts.
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		return excelize.ExcelDateToTime(n, false)
+	}
+	layouts := append(append([]string{}, runsheetDateLayouts...), weslog.DateLayouts...)
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date '%s'", value)
 }
 
 func New(fn string) (RunSheet, error) {
+	instrument, ok := InstrumentFor(fn)
+	layout := instrument.Layout
+	if !ok {
+		layout = defaultLayout
+	}
+	sheetName := layout.SheetName
 	f, err := excelize.OpenFile(fn)
 	if err != nil {
 		return RunSheet{Filename: fn}, err
@@ -59,7 +102,7 @@ func New(fn string) (RunSheet, error) {
 			dataIdx = i
 		}
 	}
-	headerRow := dataIdx + 3
+	headerRow := dataIdx + layout.HeaderOffset
 	headerMap := make(map[string]int)
 	header := Header{}
 	rows, err := f.Rows(sheetName)
@@ -90,11 +133,16 @@ func New(fn string) (RunSheet, error) {
 			if value == "" {
 				return RunSheet{Filename: fn}, fmt.Errorf("sequencing start date is empty: %s", filepath.Base(fn))
 			}
-			t, err := time.Parse("01-02-06", value)
+			// Excel dates are only recognisable from the raw value.
+			raw, err := weslog.GetRawCellValue(f, sheetName, axis)
+			if err != nil {
+				return RunSheet{Filename: fn}, err
+			}
+			t, err := parseDate(raw)
 			if err != nil {
 				return RunSheet{Filename: fn}, fmt.Errorf("unable to parse sequencing start date: %w", err)
 			}
-			header.SequencingStartDate = t.Format("02/01/2006")
+			header.SequencingStartDate = weslog.Date{Time: t}
 		case "Instrument Name":
 			header.InstrumentName = value
 		case "Run Number":
@@ -171,12 +219,14 @@ func New(fn string) (RunSheet, error) {
 		return RunSheet{Filename: fn}, fmt.Errorf("missing flowcell ID")
 	}
 	r := RunSheet{
-		Filename:  fn,
-		f:         f,
-		Header:    header,
-		headerMap: headerMap,
-		dataIdx:   dataIdx,
-		sheetName: sheetName,
+		Filename:   fn,
+		Instrument: instrument.Name,
+		f:          f,
+		Header:     header,
+		headerMap:  headerMap,
+		dataIdx:    dataIdx,
+		headerRow:  headerRow,
+		sheetName:  sheetName,
 	}
 	scanner := r.NewScanner()
 	for scanner.Scan() {
@@ -195,7 +245,7 @@ func New(fn string) (RunSheet, error) {
 func (r RunSheet) NewScanner() *Scanner {
 	return &Scanner{
 		r:      r,
-		curRow: r.dataIdx + 4,
+		curRow: r.headerRow + 1,
 	}
 }
 
@@ -338,6 +388,6 @@ func getFormattedString(r RunSheet, column string, rowIdx int) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to get coordinate: %w", err)
 	}
-	c, err := r.f.GetCellValue(sheetName, cellName)
+	c, err := r.f.GetCellValue(r.sheetName, cellName)
 	return c, err
 }
@@ -0,0 +1,265 @@
+package runsheet
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// BCLConvertOptions controls the [BCLConvert_Settings] section of a BCL
+// Convert sample sheet. Empty values are left out.
+type BCLConvertOptions struct {
+	SoftwareVersion          string
+	AdapterRead1             string
+	AdapterRead2             string
+	CreateFastqForIndexReads bool
+	// Index2AsIs writes Index2 as it is in the runsheet rather than in the
+	// orientation BCL Convert expects it.
+	Index2AsIs bool
+}
+
+// WriteBCLConvertSampleSheet writes the runsheet as a BCL Convert v2
+// SampleSheet.csv.
+func (r RunSheet) WriteBCLConvertSampleSheet(w io.Writer, opts BCLConvertOptions) error {
+	h := r.Header
+	index2, err := r.index2Func(opts.Index2AsIs, BCLConvert)
+	if err != nil {
+		return err
+	}
+	samples, err := r.ExpandLanes()
+	if err != nil {
+		return err
+	}
+	header := [][]string{
+		{"FileFormatVersion", "2"},
+		{"RunName", h.RunName},
+	}
+
+	reads := [][]string{{"Read1Cycles", strconv.Itoa(h.Read1Cycles)}}
+	if h.Read2Cycles > 0 {
+		reads = append(reads, []string{"Read2Cycles", strconv.Itoa(h.Read2Cycles)})
+	}
+	if h.I7IndexReadCycles > 0 {
+		reads = append(reads, []string{"Index1Cycles", strconv.Itoa(h.I7IndexReadCycles)})
+	}
+	if h.I5IndexReadCycles > 0 {
+		reads = append(reads, []string{"Index2Cycles", strconv.Itoa(h.I5IndexReadCycles)})
+	}
+
+	settings := [][]string{}
+	if opts.SoftwareVersion != "" {
+		settings = append(settings, []string{"SoftwareVersion", opts.SoftwareVersion})
+	}
+	if opts.AdapterRead1 != "" {
+		settings = append(settings, []string{"AdapterRead1", opts.AdapterRead1})
+	}
+	if opts.AdapterRead2 != "" {
+		settings = append(settings, []string{"AdapterRead2", opts.AdapterRead2})
+	}
+	if opts.CreateFastqForIndexReads {
+		settings = append(settings, []string{"CreateFastqForIndexReads", "1"})
+	}
+
+	// OverrideCycles is only needed when an index is shorter than the
+	// cycles read. It goes in the settings when every sample shares it and
+	// in the data otherwise.
+	overrides, err := r.overrideCycles(samples)
+	if err != nil {
+		return err
+	}
+	perSample := false
+	if len(overrides) > 0 {
+		for _, o := range overrides[1:] {
+			if o != overrides[0] {
+				perSample = true
+			}
+		}
+		if !perSample {
+			settings = append(settings, []string{"OverrideCycles", overrides[0]})
+		}
+	}
+
+	withLane := r.hasLanes()
+	withIndex2 := h.I5IndexReadCycles > 0
+	columns := []string{"Sample_ID", "Index"}
+	if withLane {
+		columns = append([]string{"Lane"}, columns...)
+	}
+	if withIndex2 {
+		columns = append(columns, "Index2")
+	}
+	if perSample {
+		columns = append(columns, "OverrideCycles")
+	}
+	data := [][]string{columns}
+	for i, s := range samples {
+		row := []string{s.ID, s.Index}
+		if withLane {
+			row = append([]string{s.Lane}, row...)
+		}
+		if withIndex2 {
+			row = append(row, index2(s))
+		}
+		if perSample {
+			row = append(row, overrides[i])
+		}
+		data = append(data, row)
+	}
+
+	cw := csv.NewWriter(w)
+	writeSection(cw, "Header", header)
+	writeSection(cw, "Reads", reads)
+	writeSection(cw, "BCLConvert_Settings", settings)
+	writeSection(cw, "BCLConvert_Data", data)
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write sample sheet: %w", err)
+	}
+	return nil
+}
+
+// Bcl2fastqOptions controls the [Header] and [Settings] sections of a bcl2fastq
+// sample sheet.
+type Bcl2fastqOptions struct {
+	// Application defaults to "FASTQ Only".
+	Application  string
+	Adapter      string
+	AdapterRead2 string
+	// Index2AsIs writes index2 as it is in the runsheet rather than in the
+	// orientation bcl2fastq expects it.
+	Index2AsIs bool
+}
+
+// WriteBcl2fastqSampleSheet writes the runsheet as an IEM style v1
+// SampleSheet.csv for bcl2fastq. The Workflow is taken from the runsheet
+// header, defaulting to "GenerateFASTQ".
+func (r RunSheet) WriteBcl2fastqSampleSheet(w io.Writer, opts Bcl2fastqOptions) error {
+	h := r.Header
+	index2, err := r.index2Func(opts.Index2AsIs, Bcl2fastq)
+	if err != nil {
+		return err
+	}
+	samples, err := r.ExpandLanes()
+	if err != nil {
+		return err
+	}
+	workflow := h.Workflow
+	if workflow == "" {
+		workflow = "GenerateFASTQ"
+	}
+	application := opts.Application
+	if application == "" {
+		application = "FASTQ Only"
+	}
+	header := [][]string{
+		{"IEMFileVersion", "4"},
+		{"Experiment Name", h.RunName},
+		{"Date", h.SequencingStartDateString()},
+		{"Workflow", workflow},
+		{"Application", application},
+	}
+
+	reads := [][]string{{strconv.Itoa(h.Read1Cycles)}}
+	if h.Read2Cycles > 0 {
+		reads = append(reads, []string{strconv.Itoa(h.Read2Cycles)})
+	}
+
+	settings := [][]string{}
+	if opts.Adapter != "" {
+		settings = append(settings, []string{"Adapter", opts.Adapter})
+	}
+	if opts.AdapterRead2 != "" {
+		settings = append(settings, []string{"AdapterRead2", opts.AdapterRead2})
+	}
+
+	withLane := r.hasLanes()
+	withIndex2 := h.I5IndexReadCycles > 0
+	columns := []string{"Sample_ID", "Sample_Name", "Sample_Project", "I7_Index_ID", "index"}
+	if withLane {
+		columns = append([]string{"Lane"}, columns...)
+	}
+	if withIndex2 {
+		columns = append(columns, "I5_Index_ID", "index2")
+	}
+	data := [][]string{columns}
+	for _, s := range samples {
+		row := []string{s.ID, s.UIN, s.ProjectID, s.I7IndexID, s.Index}
+		if withLane {
+			row = append([]string{s.Lane}, row...)
+		}
+		if withIndex2 {
+			row = append(row, s.I5IndexID, index2(s))
+		}
+		data = append(data, row)
+	}
+
+	cw := csv.NewWriter(w)
+	writeSection(cw, "Header", header)
+	writeSection(cw, "Reads", reads)
+	writeSection(cw, "Settings", settings)
+	writeSection(cw, "Data", data)
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write sample sheet: %w", err)
+	}
+	return nil
+}
+
+// overrideCycles returns the OverrideCycles of each sample, or nil if every
+// index is as long as the cycles read.
+func (r RunSheet) overrideCycles(samples []Sample) ([]string, error) {
+	overrides := make([]string, len(samples))
+	needed := false
+	for i, s := range samples {
+		o, err := r.OverrideCycles(s)
+		if err != nil {
+			return nil, err
+		}
+		if (r.Header.I7IndexReadCycles > 0 && len(s.Index) != r.Header.I7IndexReadCycles) ||
+			(r.Header.I5IndexReadCycles > 0 && len(s.Index2) != r.Header.I5IndexReadCycles) {
+			needed = true
+		}
+		overrides[i] = o
+	}
+	if !needed {
+		return nil, nil
+	}
+	return overrides, nil
+}
+
+// index2Func returns a function giving the Index2 to write for a sample in a
+// sample sheet for d. It is an error if the orientation is needed but can not
+// be worked out.
+func (r RunSheet) index2Func(asIs bool, d Demultiplexer) (func(Sample) string, error) {
+	if asIs || r.Header.I5IndexReadCycles == 0 {
+		return func(s Sample) string { return s.Index2 }, nil
+	}
+	o, err := r.SampleSheetOrientation(d)
+	if err != nil {
+		return nil, fmt.Errorf("%w: set Index2AsIs to write Index2 unchanged", err)
+	}
+	if o == OrientationReverseComplement {
+		return func(s Sample) string { return ReverseComplement(s.Index2) }, nil
+	}
+	return func(s Sample) string { return s.Index2 }, nil
+}
+
+// hasLanes reports whether any sample has a lane.
+func (r RunSheet) hasLanes() bool {
+	for _, s := range r.Samples {
+		if s.Lane != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeSection writes a [name] line followed by records. Errors are reported
+// by cw.Error.
+func writeSection(cw *csv.Writer, name string, records [][]string) {
+	cw.Write([]string{"[" + name + "]"})
+	for _, record := range records {
+		cw.Write(record)
+	}
+}
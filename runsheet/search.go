@@ -1,15 +1,12 @@
 package runsheet
 
 import (
-	"encoding/csv"
 	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 )
 
@@ -18,11 +15,13 @@ type searchResult struct {
 	err      error
 }
 
+// Find returns the runsheet files in runSheetFolder that match a registered
+// instrument, skipping any whose name is in excludeList.
 func Find(runSheetFolder string, excludeList []string) []string {
 	fs, _ := ioutil.ReadDir(runSheetFolder)
 	runSheetFiles := []string{}
 	for _, f := range fs {
-		if !f.IsDir() && strings.HasPrefix(f.Name(), "NovaSeq") && strings.HasSuffix(f.Name(), ".xlsx") {
+		if isRunsheetFile(f) {
 			if isNotExcluded(f.Name(), excludeList) {
 				fn := filepath.Join(runSheetFolder, f.Name())
 				runSheetFiles = append(runSheetFiles, fn)
@@ -33,20 +32,15 @@ func Find(runSheetFolder string, excludeList []string) []string {
 }
 
 func isRunsheetFile(f os.FileInfo) bool {
-	return !f.IsDir() && (strings.HasPrefix(f.Name(), "NovaSeq") || strings.HasPrefix(f.Name(), "NextSeq")) && strings.HasSuffix(f.Name(), ".xlsx")
+	if f.IsDir() {
+		return false
+	}
+	_, ok := InstrumentFor(f.Name())
+	return ok
 }
 
 func readRunSheets(runSheetFolder string, excludeList []string) chan searchResult {
-	fs, _ := ioutil.ReadDir(runSheetFolder)
-	runSheetFiles := []string{}
-	for _, f := range fs {
-		if isRunsheetFile(f) {
-			if isNotExcluded(f.Name(), excludeList) {
-				fn := filepath.Join(runSheetFolder, f.Name())
-				runSheetFiles = append(runSheetFiles, fn)
-			}
-		}
-	}
+	runSheetFiles := Find(runSheetFolder, excludeList)
 
 	c := make(chan searchResult)
 	var wg sync.WaitGroup
@@ -124,23 +118,14 @@ func findByParse(num, runsheetdir string) (RunSheet, error) {
 }
 
 func findByLookup(num string, runsheetdir string) (RunSheet, error) {
-	r, err := os.Open(filepath.Join(runsheetdir, "lookup.csv"))
+	entries, err := readLookup(runsheetdir)
 	if err != nil {
 		return RunSheet{}, err
 	}
-	defer r.Close()
-	reader := csv.NewReader(r)
 	fn := ""
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return RunSheet{}, err
-		}
-		if record[0] == num {
-			fn = record[1]
+	for _, e := range entries {
+		if e.RunNumber == num {
+			fn = e.Filename
 		}
 	}
 	if fn == "" {
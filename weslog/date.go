@@ -13,6 +13,17 @@ type Date struct {
 
 const dateLayout = "2006-01-02"
 
+// DateLayouts are the text forms of dates entered by hand in the WES log.
+// Numeric dates are day first.
+var DateLayouts = []string{
+	"02-Jan-2006",
+	"2-Jan-2006",
+	"02/01/2006",
+	"2/01/2006",
+	"02-Jan-06",
+	"2-Jan-06",
+}
+
 // UnmarshalJSON ...
 func (date *Date) UnmarshalJSON(b []byte) (err error) {
 	s := strings.Trim(string(b), "\"")
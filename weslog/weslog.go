@@ -371,16 +371,8 @@ func (s *Scanner) getTime(column string, rowIdx int) (time.Time, error) {
 		// If the cell's raw value can not be passed as a float, it is
 		// not a true date. Assuming it is a date in string format, try
 		// possible formats.
-		formats := []string{
-			"02-Jan-2006",
-			"2-Jan-2006",
-			"02/01/2006",
-			"2/01/2006",
-			"02-Jan-06",
-			"2-Jan-06",
-		}
 		// s := strings.TrimSpace(c)
-		for _, format := range formats {
+		for _, format := range DateLayouts {
 			t, err := time.Parse(format, c)
 			if err == nil {
 				return t, nil